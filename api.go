@@ -1,6 +1,9 @@
 package conf_reload
 
 import (
+	"context"
+	"io"
+	"io/fs"
 	"time"
 )
 
@@ -13,6 +16,73 @@ func LoadEngine(path string, opts ...Option) {
 	}
 }
 
+// LoadFromSearchPaths external exposure api to find the config file name in paths,
+// ./, $XDG_CONFIG_HOME/<app>, $HOME/.config/<app>, /etc/<app> and the parents of the working directory.
+func LoadFromSearchPaths(name string, paths []string, opts ...Option) error {
+	return defaultEngine.LoadFromSearchPaths(name, paths, opts...)
+}
+
+// LoadBytes external exposure api to load the config from data parsed by the format name.
+func LoadBytes(data []byte, name string, opts ...Option) error {
+	return defaultEngine.LoadBytes(data, name, opts...)
+}
+
+// LoadReader external exposure api to load the config read from r parsed by the format name.
+func LoadReader(r io.Reader, name string, opts ...Option) error {
+	return defaultEngine.LoadReader(r, name, opts...)
+}
+
+// LoadFS external exposure api to load the config file path of fsys, such as an embed.FS.
+func LoadFS(fsys fs.FS, path string, opts ...Option) error {
+	return defaultEngine.LoadFS(fsys, path, opts...)
+}
+
+// Reload external exposure api to reload the config synchronously.
+func Reload(ctx context.Context) error {
+	return defaultEngine.Reload(ctx)
+}
+
+// Validate external exposure api to check whether content would apply cleanly,
+// and get its Diff against the current config.
+func Validate(content []byte) (*Diff, error) {
+	return defaultEngine.Validate(content)
+}
+
+// Pause external exposure api to hold every reload until Resume.
+func Pause() {
+	defaultEngine.Pause()
+}
+
+// Resume external exposure api to release reloads held by Pause and apply the queued change.
+func Resume() error {
+	return defaultEngine.Resume()
+}
+
+// History external exposure api to get the last applied configs, oldest first.
+func History() []HistoryEntry {
+	return defaultEngine.History()
+}
+
+// Rollback external exposure api to re-apply the config of revision from the history in memory.
+func Rollback(revision uint64) error {
+	return defaultEngine.Rollback(revision)
+}
+
+// Revision external exposure api to get the revision of the current config.
+func Revision() uint64 {
+	return defaultEngine.Revision()
+}
+
+// WaitForRevision external exposure api to block until the current config is at least revision.
+func WaitForRevision(ctx context.Context, revision uint64) error {
+	return defaultEngine.WaitForRevision(ctx, revision)
+}
+
+// WaitForChange external exposure api to block until the next config is applied.
+func WaitForChange(ctx context.Context) (uint64, error) {
+	return defaultEngine.WaitForChange(ctx)
+}
+
 // Get external exposure api to get any type value.
 func Get(key string) interface{} {
 	return defaultEngine.Get(key)
@@ -83,3 +153,13 @@ func GetStringMapStringSlice(key string) map[string][]string {
 func DecodeToStruct(key string, out interface{}) error {
 	return defaultEngine.DecodeToStruct(key, out)
 }
+
+// Stats external exposure api to get the reload statistics.
+func Stats() ReloadStats {
+	return defaultEngine.Stats()
+}
+
+// Health external exposure api to get the state of the broker watch.
+func Health() HealthState {
+	return defaultEngine.Health()
+}
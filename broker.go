@@ -0,0 +1,79 @@
+package conf_reload
+
+import (
+	"github.com/enpsl/conf-reload/internal/base"
+	"github.com/enpsl/conf-reload/internal/decode"
+	"github.com/enpsl/conf-reload/internal/format"
+	"github.com/enpsl/conf-reload/internal/fs"
+	"io"
+)
+
+// Source fetches and watches the raw content of the config of an Engine
+type Source interface {
+	// LoadContent returns the current raw content of the config
+	LoadContent() ([]byte, error)
+
+	// Watch blocks until Close, sending an Event on Notify for every change of the config.
+	// Sending must not block, a pending Event not yet received should be replaced by the latest
+	Watch()
+
+	// Notify returns the channel Events are sent on, it is closed by Close
+	Notify() <-chan Event
+
+	// Health returns the watch state
+	Health() BrokerHealth
+
+	io.Closer
+}
+
+// Format parses the raw content of a Source into a config map
+type Format interface {
+	Parse(content []byte) (error, map[string]interface{})
+}
+
+// Decoder decodes the config or a value of it into output, the way DecodeToStruct does
+type Decoder interface {
+	Decode(input interface{}, output interface{}, weaklyTypedInput bool) error
+}
+
+// Broker is a Source, Format and Decoder in one.
+// The engine uses a file broker by default, set any of them with WithBroker, WithSource,
+// WithParser or WithDecoder to load the config from any other system while reusing the engine, cache and getters
+type Broker interface {
+	Source
+	Format
+	Decoder
+}
+
+// Event is a change notification sent by a Broker
+type Event = base.Event
+
+// EventReason is why an Event was sent, brokers may define their own reasons
+type EventReason = base.EventReason
+
+// BrokerHealth is the watch state of a Broker
+type BrokerHealth = base.Health
+
+const (
+	ReasonLoad          = base.ReasonLoad
+	ReasonWrite         = base.ReasonWrite
+	ReasonSymlink       = base.ReasonSymlink
+	ReasonPoll          = base.ReasonPoll
+	ReasonRecover       = base.ReasonRecover
+	ReasonManual        = base.ReasonManual
+	ReasonSignal        = base.ReasonSignal
+	ReasonRollback      = base.ReasonRollback
+	ReasonLastKnownGood = base.ReasonLastKnownGood
+)
+
+// NewEvent returns an Event of name seen now
+func NewEvent(name string, reason EventReason) Event {
+	return base.NewEvent(name, reason)
+}
+
+var _ Broker = (*fs.FsBroker)(nil)
+var _ Source = (*fs.FsSource)(nil)
+var _ Source = (*fs.StaticSource)(nil)
+var _ Format = (*format.Format)(nil)
+var _ Decoder = (*format.Format)(nil)
+var _ Decoder = (*decode.Decoder)(nil)
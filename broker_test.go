@@ -0,0 +1,139 @@
+package conf_reload
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// memBroker serves a JSON config from memory
+type memBroker struct {
+	mu       sync.Mutex
+	content  []byte
+	notifyCh chan Event
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newMemBroker(content string) *memBroker {
+	return &memBroker{content: []byte(content), notifyCh: make(chan Event, 1), done: make(chan struct{})}
+}
+
+func (b *memBroker) set(content string) {
+	b.mu.Lock()
+	b.content = []byte(content)
+	b.mu.Unlock()
+	b.notifyCh <- NewEvent("memory", EventReason("set"))
+}
+
+func (b *memBroker) Parse(content []byte) (error, map[string]interface{}) {
+	m := make(map[string]interface{})
+	return json.Unmarshal(content, &m), m
+}
+
+func (b *memBroker) LoadContent() ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.content, nil
+}
+
+func (b *memBroker) Watch() { <-b.done }
+
+func (b *memBroker) Decode(input interface{}, output interface{}, weaklyTypedInput bool) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, output)
+}
+
+func (b *memBroker) Notify() <-chan Event { return b.notifyCh }
+
+func (b *memBroker) Health() BrokerHealth { return BrokerHealth{Watching: true} }
+
+func (b *memBroker) Close() error {
+	b.once.Do(func() {
+		close(b.done)
+		close(b.notifyCh)
+	})
+	return nil
+}
+
+func TestEngineWithBroker(t *testing.T) {
+	b := newMemBroker(`{"server": {"http": {"host": "0.0.0.0", "port": 8080}}}`)
+	defer b.Close()
+	e := NewEngine()
+	if err := e.Load("", WithBroker(b), WithLogLevel(1)); err != nil {
+		t.Fatal(err)
+	}
+	var http = &Http{}
+	if err := e.DecodeToStruct("server.http", http); err != nil {
+		t.Fatal(err)
+	}
+	if http.Port != 8080 {
+		t.Errorf("got=%d, want=%d", http.Port, 8080)
+	}
+
+	b.set(`{"server": {"http": {"host": "0.0.0.0", "port": 8081}}}`)
+	if err := e.WaitForRevision(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetInt("server.http.port"); got != 8081 {
+		t.Errorf("got=%d, want=%d", got, 8081)
+	}
+}
+
+func TestEngineWithSource(t *testing.T) {
+	// TOML served from memory, parsed and decoded by the format of the path ext
+	b := newMemBroker("[server.http]\nhost = \"0.0.0.0\"\nport = 8080")
+	defer b.Close()
+	e := NewEngine()
+	if err := e.Load("app.toml", WithSource(b), WithWatched(false), WithLogLevel(1)); err != nil {
+		t.Fatal(err)
+	}
+	var http = &Http{}
+	if err := e.DecodeToStruct("server.http", http); err != nil {
+		t.Fatal(err)
+	}
+	if http.Port != 8080 || http.Host != "0.0.0.0" {
+		t.Errorf("host=%s, want=%s| port=%d, want=%d", http.Host, "0.0.0.0", http.Port, 8080)
+	}
+}
+
+func TestEngineLoadRebuildsComponents(t *testing.T) {
+	b := newMemBroker(`{"port": 8080}`)
+	e := NewEngine()
+	if err := e.Load("", WithBroker(b), WithWatched(false), WithLogLevel(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "app.toml")
+	if err := os.WriteFile(path, []byte("port = 8081"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetInt("port"); got != 8081 {
+		t.Errorf("got=%d, want=%d", got, 8081)
+	}
+	select {
+	case <-b.done:
+	default:
+		t.Error("source of the previous Load not closed")
+	}
+
+	path = filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(path, []byte(`{"port": 8082}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetInt("port"); got != 8082 {
+		t.Errorf("got=%d, want=%d", got, 8082)
+	}
+}
@@ -0,0 +1,84 @@
+package conf_reload
+
+import (
+	"github.com/spf13/cast"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Diff is the difference between two configs,
+// keyed by the paths of the leaf values joined with LevelSplit
+type Diff struct {
+	Added   map[string]interface{} // keys only in the new config
+	Removed map[string]interface{} // keys only in the old config
+	Changed map[string]Change      // keys whose value changed
+}
+
+// Change is the old and new value of a changed key
+type Change struct {
+	Old interface{}
+	New interface{}
+}
+
+// Empty reports whether the configs are equal
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Keys returns every added, removed and changed key in order
+func (d *Diff) Keys() []string {
+	keys := make([]string, 0, len(d.Added)+len(d.Removed)+len(d.Changed))
+	for k := range d.Added {
+		keys = append(keys, k)
+	}
+	for k := range d.Removed {
+		keys = append(keys, k)
+	}
+	for k := range d.Changed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// diff compares the leaf values of current and candidate
+func (e *Engine) diff(current, candidate map[string]interface{}) *Diff {
+	d := &Diff{
+		Added:   make(map[string]interface{}),
+		Removed: make(map[string]interface{}),
+		Changed: make(map[string]Change),
+	}
+	oldLeaves := e.flatten(current, nil, make(map[string]interface{}))
+	newLeaves := e.flatten(candidate, nil, make(map[string]interface{}))
+	for k, v := range newLeaves {
+		o, ok := oldLeaves[k]
+		switch {
+		case !ok:
+			d.Added[k] = v
+		case !reflect.DeepEqual(o, v):
+			d.Changed[k] = Change{Old: o, New: v}
+		}
+	}
+	for k, v := range oldLeaves {
+		if _, ok := newLeaves[k]; !ok {
+			d.Removed[k] = v
+		}
+	}
+	return d
+}
+
+// flatten collects the leaf values of m into leaves, keyed by their path joined with LevelSplit
+func (e *Engine) flatten(m map[string]interface{}, prefix []string, leaves map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		path := append(append([]string{}, prefix...), k)
+		if reflect.ValueOf(v).Kind() == reflect.Map {
+			if nested, err := cast.ToStringMapE(v); err == nil && len(nested) > 0 {
+				e.flatten(nested, path, leaves)
+				continue
+			}
+		}
+		leaves[strings.Join(path, e.LevelSplit)] = v
+	}
+	return leaves
+}
@@ -1,12 +1,18 @@
 package conf_reload
 
 import (
+	"context"
+	"fmt"
 	"github.com/enpsl/conf-reload/internal/app"
 	"github.com/enpsl/conf-reload/internal/base"
 	"github.com/enpsl/conf-reload/internal/errors"
+	"github.com/enpsl/conf-reload/internal/format"
 	"github.com/enpsl/conf-reload/internal/fs"
 	"github.com/enpsl/conf-reload/internal/log"
 	"github.com/spf13/cast"
+	"io"
+	iofs "io/fs"
+	"os"
 	"reflect"
 	"strings"
 	"sync"
@@ -22,9 +28,44 @@ type Engine struct {
 	Logger           *log.Logger            // logger instance
 	LocalStorage     *base.LRUCache         // fast cache
 	Configure        map[string]interface{} // original config
-	Broker           base.Broker            // broker
+	Source           Source                 // config content source
+	Format           Format                 // config content parser
+	Decoder          Decoder                // config struct decoder
+	FormatName       string                 // format parsing the config file whatever its ext, FormatAuto sniffs the content
+	EnvSeparator     string                 // splits dotenv keys into nested keys, empty keeps them flat
+	XMLAttrPrefix    string                 // prefixes the keys of xml attributes
 	Capacity         int                    // LRU Cache cap
 	Watched          bool                   // watched switch
+	PollInterval     time.Duration          // polling watcher interval, 0 means fsnotify with polling fallback
+	Debounce         time.Duration          // quiet period coalescing change events, 0 disables debouncing
+	DebounceMaxWait  time.Duration          // max delay of a debounced reload, 0 means no cap
+	ReloadSignals    []os.Signal            // signals triggering a Reload
+	signals          chan os.Signal         // receives ReloadSignals, replaced on every Load
+	Validators       []Validator            // checks run on every config before it is applied
+	ReloadWindows    []ReloadWindow         // reloads outside these windows are held, empty means always
+	ReloadInterval   time.Duration          // min interval between reloads, 0 means no rate limit
+	ReloadBurst      int                    // reloads allowed at once before ReloadInterval applies
+	path             string                 // config file path
+	reloadMu         sync.Mutex             // serializes reloads
+	holdMu           sync.Mutex             // guards the hold state below
+	paused           bool                   // reloads held by Pause
+	pending          *base.Event            // latest held event
+	pendingSince     time.Time              // when the first held event was queued
+	heldReason       string                 // why the pending event is held
+	releaseTimer     *time.Timer            // applies the pending event once reloads are released
+	tokens           float64                // reload rate limiter tokens
+	lastRefill       time.Time              // when tokens were last refilled
+	lastHeld         time.Time              // when a reload was last held by the rate limiter
+	flapping         bool                   // the rate limiter is holding reloads
+	HistorySize      int                    // applied configs kept for Rollback
+	history          []HistoryEntry         // applied configs, oldest first
+	revision         uint64                 // revision of the current config
+	changed          chan struct{}          // closed and replaced on every applied config
+	LastKnownGood    string                 // file persisting the last applied config, used when Load fails
+	lkgReason        string                 // why the config was loaded from LastKnownGood
+	rawSum           base.Sum               // hash of RawData
+	confSum          base.Sum               // hash of Configure
+	stats            ReloadStats            // reload statistics
 }
 
 type Option func(*Engine)
@@ -91,98 +132,464 @@ func WithWatched(watched bool) Option {
 	}
 }
 
+// WithPollInterval Broker polling options
+// Watch the config file by polling at interval instead of fsnotify,
+// for file systems where fsnotify does not fire such as NFS, FUSE and bind mounts.
+// Without it, polling is only used when fsnotify can not watch the config dir
+func WithPollInterval(interval time.Duration) Option {
+	return func(engine *Engine) {
+		engine.PollInterval = interval
+	}
+}
+
+// WithDebounce Broker debounce options
+// Editors write a file in several steps, the burst of change events is coalesced into one reload
+// once no event arrived for window, or at the latest maxWait after the first event
+// so a constantly written file still reloads. maxWait 0 means no cap
+func WithDebounce(window, maxWait time.Duration) Option {
+	return func(engine *Engine) {
+		engine.Debounce = window
+		engine.DebounceMaxWait = maxWait
+	}
+}
+
+// WithReloadSignal Reload the config when the process receives one of sigs, e.g. syscall.SIGHUP
+func WithReloadSignal(sigs ...os.Signal) Option {
+	return func(engine *Engine) {
+		engine.ReloadSignals = sigs
+	}
+}
+
+// WithValidator Reject every config for which v returns an error,
+// a rejected config is not applied and the current config is kept
+func WithValidator(v Validator) Option {
+	return func(engine *Engine) {
+		engine.Validators = append(engine.Validators, v)
+	}
+}
+
+// WithStructValidator Reject every config whose value of key can not be decoded into the type of prototype,
+// the same way DecodeToStruct decodes it. An empty key validates the whole config
+func WithStructValidator(key string, prototype interface{}) Option {
+	return func(engine *Engine) {
+		engine.Validators = append(engine.Validators, func(config map[string]interface{}) error {
+			return engine.decodeCheck(config, key, prototype)
+		})
+	}
+}
+
+// WithReloadWindows Hold reloads outside windows, a held change is applied once the next window opens
+func WithReloadWindows(windows ...ReloadWindow) Option {
+	return func(engine *Engine) {
+		engine.ReloadWindows = windows
+	}
+}
+
+// WithReloadRateLimit Allow burst reloads at once, then one per minInterval.
+// A config rewritten faster is flapping: a warning is logged,
+// and the latest change is held until no change arrived for minInterval
+func WithReloadRateLimit(minInterval time.Duration, burst int) Option {
+	return func(engine *Engine) {
+		engine.ReloadInterval = minInterval
+		engine.ReloadBurst = burst
+	}
+}
+
+// WithHistorySize Keep the last size applied configs for Rollback
+func WithHistorySize(size int) Option {
+	return func(engine *Engine) {
+		engine.HistorySize = size
+	}
+}
+
+// WithLastKnownGood Persist every applied config to path.
+// When the config file fails to load or parse on Load, the engine starts from path instead
+// and reports itself degraded until the config file applies again
+func WithLastKnownGood(path string) Option {
+	return func(engine *Engine) {
+		engine.LastKnownGood = path
+	}
+}
+
+// WithBroker Load, parse and decode the config with b instead of a file
+func WithBroker(b Broker) Option {
+	return func(engine *Engine) {
+		engine.Source = b
+		engine.Format = b
+		engine.Decoder = b
+	}
+}
+
+// WithSource Load the config from s instead of a file
+func WithSource(s Source) Option {
+	return func(engine *Engine) {
+		engine.Source = s
+	}
+}
+
+// WithParser Parse the config with f instead of the format of the file ext
+func WithParser(f Format) Option {
+	return func(engine *Engine) {
+		engine.Format = f
+	}
+}
+
+// WithFormat Parse the config file with the format name whatever its ext, such as "yaml" for app.conf.
+// FormatAuto detects json, toml or yaml from the content at Load, and reports content it can not tell apart
+func WithFormat(name string) Option {
+	return func(engine *Engine) {
+		engine.FormatName = name
+	}
+}
+
+// WithEnvSeparator Split the keys of a dotenv file on separator into nested keys,
+// such as "__" for SERVER__HTTP__PORT read as server.http.port. Keys are kept flat by default
+func WithEnvSeparator(separator string) Option {
+	return func(engine *Engine) {
+		engine.EnvSeparator = separator
+	}
+}
+
+// WithXMLAttrPrefix Key the attributes of xml elements by their name after prefix, "@" by default,
+// so <server port="80"/> is read as server.@port.
+// DecodeToStruct reads the xml tags as encoding/xml does whatever the prefix:
+// a `xml:"port,attr"` field is the attribute, a `xml:",chardata"` field the text of the element.
+// With an empty prefix, an attribute and a child element of the same name fail to parse
+func WithXMLAttrPrefix(prefix string) Option {
+	return func(engine *Engine) {
+		engine.XMLAttrPrefix = prefix
+	}
+}
+
+// WithDecoder Decode the config into structs with d
+func WithDecoder(d Decoder) Option {
+	return func(engine *Engine) {
+		engine.Decoder = d
+	}
+}
+
 // NewEngine
 // Engine init
 func NewEngine() *Engine {
 	return &Engine{
-		Logger:     log.NewLogger(nil),
-		LevelSplit: app.DefaultLevelSplit,
-		Configure:  make(map[string]interface{}),
-		Capacity:   app.DefaultCapacity,
-		Watched:    true,
+		Logger:        log.NewLogger(nil),
+		LevelSplit:    app.DefaultLevelSplit,
+		Configure:     make(map[string]interface{}),
+		Capacity:      app.DefaultCapacity,
+		XMLAttrPrefix: app.DefaultXMLAttrPrefix,
+		Watched:       true,
+		HistorySize:   app.DefaultHistorySize,
+		changed:       make(chan struct{}),
 	}
 }
 
 // Load the configuration file information and initialize the broker.
-// The broker will start an additional process to receive the file change chan notification
+// The broker will start an additional process to receive the file change chan notification.
+// Components not set by WithBroker, WithSource, WithParser or WithDecoder of this call are taken
+// from a file broker of path, path only names the config in events when all are set.
+// Every Load rebuilds the components and closes the Source of the previous Load,
+// validators are those of WithValidator and WithStructValidator of this call,
+// the process exits if the config can not be loaded
 func (e *Engine) Load(path string, opts ...Option) error {
+	if err := e.load(path, opts...); err != nil {
+		e.Logger.Fatal(err)
+	}
+	return nil
+}
+
+// load
+// Load without exiting: if the config can not be loaded,
+// the components of the previous Load are kept and the error is returned
+func (e *Engine) load(path string, opts ...Option) error {
+	e.reloadMu.Lock()
+	e.mu.Lock()
+	prevSource, prevFormat, prevDecoder, prevFormatName, prevPath := e.Source, e.Format, e.Decoder, e.FormatName, e.path
+	prevValidators := e.Validators
+	e.Source, e.Format, e.Decoder, e.FormatName, e.Validators = nil, nil, nil, "", nil
 	for _, opt := range opts {
 		opt(e)
 	}
-
-	e.LocalStorage = base.CacheConstructor(e.Capacity)
-
-	err, broker := fs.NewFs(path, e.Logger)
-	if err != nil {
-		e.Logger.Fatal(err)
+	e.path = path
+	err := e.loadFsBroker(path)
+	e.mu.Unlock()
+
+	cache := base.CacheConstructor(e.Capacity)
+	if err == nil {
+		var content []byte
+		if content, err = e.Source.LoadContent(); err == nil {
+			if err = e.detectFormat(content); err == nil {
+				err = e.apply(content, base.NewEvent(path, base.ReasonLoad))
+			}
+		}
+		if err != nil && e.LastKnownGood != "" {
+			err = e.loadLastKnownGood(err)
+		}
 	}
 
-	e.Broker = broker
-
-	content, err := e.Broker.LoadContent()
-
 	if err != nil {
-		e.Logger.Fatal(err)
+		e.mu.Lock()
+		if e.Source != nil && e.Source != prevSource {
+			_ = e.Source.Close()
+		}
+		e.Source, e.Format, e.Decoder, e.FormatName, e.path = prevSource, prevFormat, prevDecoder, prevFormatName, prevPath
+		e.Validators = prevValidators
+		e.mu.Unlock()
+		e.reloadMu.Unlock()
+		return err
 	}
-
-	err = e.apply(content)
-
-	if err != nil {
-		e.Logger.Fatal(err)
+	if prevSource != nil && prevSource != e.Source {
+		_ = prevSource.Close()
 	}
+	e.mu.Lock()
+	e.LocalStorage = cache
+	e.mu.Unlock()
+	source := e.Source
+	e.watchSignals()
+	e.reloadMu.Unlock()
 
 	if !e.Watched {
 		return nil
 	}
-	go e.Broker.Watch()
+	go source.Watch()
 	go func() {
-		for range e.Broker.Notify() {
-			if content, err := e.Broker.LoadContent(); err == nil {
-				err = e.apply(content)
-				if err != nil {
-					e.Logger.Error(err)
-				}
+		for event := range source.Notify() {
+			if err := e.reload(context.Background(), event); err != nil && !errors.Is(err, ErrReloadHeld) {
+				e.Logger.Error(err)
 			}
 		}
 	}()
 	return nil
 }
 
+// loadFsBroker
+// Fill the components not set yet from the file of path:
+// the format of its ext or FormatName parses and decodes it, nesting flat keys on LevelSplit or EnvSeparator,
+// a FsSource watches it
+func (e *Engine) loadFsBroker(path string) error {
+	if e.Format == nil || e.Decoder == nil {
+		err, f := format.New(path)
+		if e.FormatName != "" {
+			err, f = format.Named(e.FormatName)
+		}
+		if err != nil {
+			return err
+		}
+		f = f.WithOptions(format.Options{
+			LevelSplit:    e.LevelSplit,
+			EnvSeparator:  e.EnvSeparator,
+			XMLAttrPrefix: e.XMLAttrPrefix,
+		})
+		if e.Format == nil {
+			e.Format = f
+		}
+		if e.Decoder == nil {
+			e.Decoder = f
+		}
+	}
+	if e.Source == nil {
+		err, source := fs.NewSource(path, e.Logger,
+			fs.WithPollInterval(e.PollInterval),
+			fs.WithDebounce(e.Debounce, e.DebounceMaxWait),
+		)
+		if err != nil {
+			return err
+		}
+		e.Source = source
+	}
+	return nil
+}
+
+// detectFormat
+// Fix a Format detecting the format of the content, such as FormatAuto, to the format of content,
+// so that reloads, Validate and DecodeToStruct keep the format detected at Load
+func (e *Engine) detectFormat(content []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	f, ok := e.Format.(*format.Format)
+	if !ok {
+		return nil
+	}
+	err, detected := f.Detect(content)
+	if err != nil || detected == f {
+		return err
+	}
+	if d, ok := e.Decoder.(*format.Format); ok && d == f {
+		e.Decoder = detected
+	}
+	e.Format = detected
+	return nil
+}
+
+// LoadFromSearchPaths
+// Find the config file name in paths and the default search directories, then Load it.
+// If nothing is found, the error lists every location that was attempted
+func (e *Engine) LoadFromSearchPaths(name string, paths []string, opts ...Option) error {
+	path, err := fs.Search(name, paths...)
+	if err != nil {
+		return err
+	}
+	return e.Load(path, opts...)
+}
+
+// LoadBytes
+// Load the config from data parsed by the format name, such as "yaml" or FormatAuto.
+// data never changes, so nothing is watched. Unlike Load, errors are returned
+func (e *Engine) LoadBytes(data []byte, name string, opts ...Option) error {
+	opts = append([]Option{WithFormat(name)}, opts...)
+	return e.load("bytes", append(opts, WithSource(fs.NewStatic(data)))...)
+}
+
+// LoadReader
+// Load the config read from r, parsed by the format name, such as "yaml" or FormatAuto.
+// r is read once, nothing is watched. Unlike Load, errors are returned
+func (e *Engine) LoadReader(r io.Reader, name string, opts ...Option) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return errors.ErrFormat(errors.ErrInvalidFilePath, fmt.Errorf("read config: %w", err))
+	}
+	opts = append([]Option{WithFormat(name)}, opts...)
+	return e.load("reader", append(opts, WithSource(fs.NewStatic(data)))...)
+}
+
+// LoadFS
+// Load the config file path of fsys, such as an embed.FS, parsed by the format of its ext.
+// The file is read once, nothing is watched. Unlike Load, errors are returned
+func (e *Engine) LoadFS(fsys iofs.FS, path string, opts ...Option) error {
+	data, err := iofs.ReadFile(fsys, path)
+	if err != nil {
+		return errors.ErrFormat(errors.ErrInvalidFilePath, fmt.Errorf("read config: %w", err))
+	}
+	return e.load(path, append(opts, WithSource(fs.NewStatic(data)))...)
+}
+
 // apply
 // Each time the configuration file changes,
-// This method will be called to delete LocalStorage and update Configure
-func (e *Engine) apply(content []byte) error {
+// This method will be called to delete LocalStorage and update Configure.
+// Content identical to RawData, or parsed to the same Configure, is skipped.
+// A Load may change the format, its options or the validators, so its content is always parsed and validated
+func (e *Engine) apply(content []byte, event base.Event) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	e.RawData = content
-	err, m := e.Broker.Parse(content)
+	rawSum := base.SumBytes(content)
+	loaded := event.Reason == base.ReasonLoad || event.Reason == base.ReasonLastKnownGood
+	if e.stats.Applied > 0 && !loaded && rawSum == e.rawSum {
+		e.stats.Skipped++
+		e.recovered(event)
+		e.Logger.Debug("config content unchanged, skip reload")
+		return nil
+	}
+	err, m := e.Format.Parse(content)
 	if err != nil {
+		e.stats.Failed++
 		return err
 	}
+	if err = e.validate(m); err != nil {
+		e.stats.Failed++
+		return err
+	}
+	e.RawData = content
+	e.rawSum = rawSum
+	confSum := base.SumMap(m)
+	if e.stats.Applied > 0 && confSum == e.confSum {
+		e.stats.Skipped++
+		e.recovered(event)
+		e.Logger.Debug("config unchanged after parse, skip reload")
+		return nil
+	}
+	e.commit(content, m, event)
+	e.recovered(event)
+	return nil
+}
+
+// commit
+// Make m the current config and record it in the history, e.mu must be held
+func (e *Engine) commit(content []byte, m map[string]interface{}, event base.Event) {
+	e.RawData = content
+	e.rawSum = base.SumBytes(content)
 	e.Configure = m
-	e.LocalStorage.Flush()
+	e.confSum = base.SumMap(m)
+	if e.LocalStorage != nil {
+		e.LocalStorage.Flush()
+	}
+	e.stats.Applied++
+	e.stats.LastApplied = time.Now()
+	e.revision++
+	e.record(HistoryEntry{
+		Revision:  e.revision,
+		RawData:   content,
+		Configure: m,
+		Time:      e.stats.LastApplied,
+		Source:    fmt.Sprintf("%s %s", event.Reason, event.Name),
+	})
+	close(e.changed)
+	e.changed = make(chan struct{})
+	e.persist(content, event)
 	e.Logger.Debug(e.Configure)
-	return nil
+}
+
+// Health returns the state of the broker watch
+func (e *Engine) Health() HealthState {
+	e.mu.RLock()
+	source := e.Source
+	e.mu.RUnlock()
+	if source == nil {
+		return HealthState{}
+	}
+	h := source.Health()
+	e.holdMu.Lock()
+	defer e.holdMu.Unlock()
+	health := HealthState{
+		Watching: h.Watching,
+		Degraded: h.Degraded,
+		Reason:   h.Reason,
+		Since:    h.Since,
+		Paused:   e.paused,
+	}
+	e.mu.RLock()
+	if e.lkgReason != "" {
+		health.Degraded = true
+		health.Reason = e.lkgReason
+	}
+	e.mu.RUnlock()
+	if e.pending != nil {
+		health.Pending = true
+		health.PendingSince = e.pendingSince
+		health.HeldReason = e.heldReason
+	}
+	return health
+}
+
+// Stats returns the reload statistics
+func (e *Engine) Stats() ReloadStats {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.stats
 }
 
 // Get the value corresponding to the key from LocalStorage.
 // If it is not available, it will be found in the broker
 func (e *Engine) Get(key string) interface{} {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
 	local, ok := e.LocalStorage.Get(key)
 	if ok {
 		return local
 	}
 
-	paths := strings.Split(key, e.LevelSplit)
-	e.mu.RLock()
-	defer e.mu.RUnlock()
-	m := e.deepSearch(e.Configure, paths[:len(paths)-1]...)
-	e.Logger.Debug(m)
-	deep := m[paths[len(paths)-1]]
+	deep := e.lookup(e.Configure, key)
 	e.LocalStorage.Put(key, deep)
 	return deep
 }
 
+// lookup
+// Get the value corresponding to the key from config
+func (e *Engine) lookup(config map[string]interface{}, key string) interface{} {
+	paths := strings.Split(key, e.LevelSplit)
+	m := e.deepSearch(config, paths[:len(paths)-1]...)
+	e.Logger.Debug(m)
+	return m[paths[len(paths)-1]]
+}
+
 // deepSearch
 // Copy m to a new map
 // This map will continuously save the value of the latest path level map during the iterative search process
@@ -224,13 +631,16 @@ func (e *Engine) DecodeToStruct(key string, i interface{}) error {
 	if key == "" {
 		e.mu.RLock()
 		defer e.mu.RUnlock()
-		return e.Broker.Decode(e.Configure, i, e.WeaklyTypedInput)
+		return e.Decoder.Decode(e.Configure, i, e.WeaklyTypedInput)
 	}
 	value := e.Get(key)
 	if value == nil {
 		return errors.ErrFormat(errors.ErrInvalidKey, nil)
 	}
-	return e.Broker.Decode(value, i, e.WeaklyTypedInput)
+	e.mu.RLock()
+	decoder := e.Decoder
+	e.mu.RUnlock()
+	return decoder.Decode(value, i, e.WeaklyTypedInput)
 }
 
 // Engine.GetInt returns the value associated with the key as string type.
@@ -0,0 +1,66 @@
+package conf_reload
+
+import (
+	"github.com/enpsl/conf-reload/internal/base"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestEngine loads content from a temp file of the given ext without watching it
+func newTestEngine(t *testing.T, ext, content string, opts ...Option) (*Engine, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test"+ext)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine()
+	opts = append([]Option{WithWatched(false), WithLogLevel(1)}, opts...)
+	if err := e.Load(path, opts...); err != nil {
+		t.Fatal(err)
+	}
+	return e, path
+}
+
+func TestEngineApplySkipUnchanged(t *testing.T) {
+	e, _ := newTestEngine(t, ".toml", "port = 8080\nhost = \"0.0.0.0\"")
+
+	tests := []struct {
+		desc    string
+		content string
+		applied uint64
+		skipped uint64
+	}{
+		{
+			desc:    "identical content",
+			content: "port = 8080\nhost = \"0.0.0.0\"",
+			applied: 1,
+			skipped: 1,
+		},
+		{
+			desc:    "format only change",
+			content: "host    = \"0.0.0.0\"\n# comment\nport = 8080\n",
+			applied: 1,
+			skipped: 2,
+		},
+		{
+			desc:    "value change",
+			content: "host = \"0.0.0.0\"\nport = 8081\n",
+			applied: 2,
+			skipped: 2,
+		},
+	}
+	for _, tc := range tests {
+		if err := e.apply([]byte(tc.content), base.NewEvent("test", base.ReasonManual)); err != nil {
+			t.Fatalf("%s: %v", tc.desc, err)
+		}
+		stats := e.Stats()
+		if stats.Applied != tc.applied || stats.Skipped != tc.skipped {
+			t.Errorf("%s: applied=%d, want=%d| skipped=%d, want=%d",
+				tc.desc, stats.Applied, tc.applied, stats.Skipped, tc.skipped)
+		}
+	}
+	if got := e.GetInt("port"); got != 8081 {
+		t.Errorf("got=%d, want=%d", got, 8081)
+	}
+}
@@ -0,0 +1,25 @@
+package conf_reload
+
+import (
+	"github.com/enpsl/conf-reload/internal/format"
+)
+
+// Unmarshaller parses config content into the map pointed to by v, like json.Unmarshal
+type Unmarshaller = format.Unmarshaller
+
+// Marshaller encodes a config map into content, like json.Marshal
+type Marshaller = format.Marshaller
+
+// RegisterFormat adds the format name for config files of exts, such as []string{".conf"},
+// so that Load and LoadFromSearchPaths parse them with unmarshal.
+// Registering an existing name or ext overrides it, the struct tag read by DecodeToStruct is name.
+// marshal may be nil if the format is only read
+func RegisterFormat(name string, exts []string, unmarshal Unmarshaller, marshal Marshaller) error {
+	return format.Register(name, exts, unmarshal, marshal)
+}
+
+// FormatAuto is the format name of WithFormat detecting json, toml or yaml from the content.
+// Detection happens once, at Load: reloads, Validate and DecodeToStruct keep the detected format,
+// so a file rewritten in another format fails to reload with the parse error of the detected one
+// until the next Load
+const FormatAuto = format.Auto
@@ -0,0 +1,211 @@
+package conf_reload
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/enpsl/conf-reload/internal/format"
+	"github.com/enpsl/conf-reload/internal/fs"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegisterFormat(t *testing.T) {
+	t.Cleanup(format.Snapshot())
+	if err := RegisterFormat("jcfg", []string{"jcfg"}, json.Unmarshal, json.Marshal); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := newTestEngine(t, ".jcfg", `{"server": {"http": {"host": "0.0.0.0", "port": 8080}}}`)
+	if got := e.GetInt("server.http.port"); got != 8080 {
+		t.Errorf("got=%d, want=%d", got, 8080)
+	}
+	var server = &struct {
+		Host string `jcfg:"host"`
+	}{}
+	if err := e.DecodeToStruct("server.http", server); err != nil {
+		t.Fatal(err)
+	}
+	if server.Host != "0.0.0.0" {
+		t.Errorf("got=%s, want=%s", server.Host, "0.0.0.0")
+	}
+}
+
+func TestRegisterFormatInvalid(t *testing.T) {
+	if err := RegisterFormat("", []string{".x"}, json.Unmarshal, nil); err == nil {
+		t.Error("format without a name registered")
+	}
+	if err := RegisterFormat("x", []string{".x"}, nil, nil); err == nil {
+		t.Error("format without an unmarshaller registered")
+	}
+}
+
+func TestWithFormat(t *testing.T) {
+	tests := []struct {
+		desc   string
+		ext    string
+		format string
+	}{
+		{desc: "forced", ext: ".conf", format: "yaml"},
+		{desc: "auto without ext", ext: "", format: FormatAuto},
+	}
+	for _, tc := range tests {
+		e, _ := newTestEngine(t, tc.ext, "server:\n  http:\n    host: 0.0.0.0\n    port: 8080", WithFormat(tc.format))
+		var http = &Http{}
+		if err := e.DecodeToStruct("server.http", http); err != nil {
+			t.Fatalf("%s: %v", tc.desc, err)
+		}
+		if http.Port != 8080 || http.Host != "0.0.0.0" {
+			t.Errorf("%s: host=%s, want=%s| port=%d, want=%d", tc.desc, http.Host, "0.0.0.0", http.Port, 8080)
+		}
+	}
+}
+
+func TestHclFormat(t *testing.T) {
+	e, _ := newTestEngine(t, ".hcl", "server \"http\" {\n  host = \"0.0.0.0\"\n  port = 8080\n}")
+	if got := e.GetInt("server.http.port"); got != 8080 {
+		t.Errorf("got=%d, want=%d", got, 8080)
+	}
+	var http = &Http{}
+	if err := e.DecodeToStruct("server.http", http); err != nil {
+		t.Fatal(err)
+	}
+	if http.Host != "0.0.0.0" {
+		t.Errorf("got=%s, want=%s", http.Host, "0.0.0.0")
+	}
+}
+
+func TestIniFormatLevelSplit(t *testing.T) {
+	tests := []struct {
+		ext     string
+		content string
+		split   string
+		key     string
+	}{
+		{ext: ".ini", content: "[server.http]\nport = 8080", split: ".", key: "server.http.port"},
+		{ext: ".ini", content: "[server/http]\nport = 8080", split: "/", key: "server/http/port"},
+		{ext: ".properties", content: "server.http.port=8080", split: ".", key: "server.http.port"},
+		{ext: ".properties", content: "server/http/port=8080", split: "/", key: "server/http/port"},
+	}
+	for _, tc := range tests {
+		e, _ := newTestEngine(t, tc.ext, tc.content, WithLevelSplit(tc.split))
+		if got := e.GetInt(tc.key); got != 8080 {
+			t.Errorf("%s %s: got=%d, want=%d", tc.ext, tc.key, got, 8080)
+		}
+	}
+}
+
+func TestDotenvFormatReload(t *testing.T) {
+	e, path := newTestEngine(t, ".env", "SERVER__HTTP__HOST=0.0.0.0\nSERVER__HTTP__PORT=8080",
+		WithEnvSeparator("__"), WithWeaklyTypedInput(true), WithWatched(true))
+	defer e.Source.Close()
+	var http = &Http{}
+	if err := e.DecodeToStruct("server.http", http); err != nil {
+		t.Fatal(err)
+	}
+	if http.Port != 8080 || http.Host != "0.0.0.0" {
+		t.Errorf("host=%s, want=%s| port=%d, want=%d", http.Host, "0.0.0.0", http.Port, 8080)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for !e.Health().Watching {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := fs.WriteFileAtomic(path, []byte("SERVER__HTTP__PORT=8081"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.WaitForRevision(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetInt("server.http.port"); got != 8081 {
+		t.Errorf("got=%d, want=%d", got, 8081)
+	}
+}
+
+func TestJsoncFormat(t *testing.T) {
+	for _, ext := range []string{".jsonc", ".json5"} {
+		e, _ := newTestEngine(t, ext, "{\n  // the http server\n  \"server\": {\"http\": {host: \"0.0.0.0\", listen_port: 8080,}},\n}")
+		var http = &struct {
+			Host       string `json:"host"`
+			ListenPort int    `json:"listen_port"`
+		}{}
+		if err := e.DecodeToStruct("server.http", http); err != nil {
+			t.Fatal(err)
+		}
+		if http.ListenPort != 8080 || http.Host != "0.0.0.0" {
+			t.Errorf("%s: host=%s, want=%s| port=%d, want=%d", ext, http.Host, "0.0.0.0", http.ListenPort, 8080)
+		}
+	}
+}
+
+func TestXmlFormat(t *testing.T) {
+	content := `<config><server name="http"><host>0.0.0.0</host><port>8080</port><motd lang="en">hello</motd></server><depend>tcp</depend><depend>ip</depend></config>`
+	e, _ := newTestEngine(t, ".xml", content, WithWeaklyTypedInput(true))
+	if got := e.GetString("config.server.@name"); got != "http" {
+		t.Errorf("got=%s, want=%s", got, "http")
+	}
+	if got := e.GetInt("config.server.port"); got != 8080 {
+		t.Errorf("got=%d, want=%d", got, 8080)
+	}
+	if got := e.GetStringSlice("config.depend"); len(got) != 2 || got[1] != "ip" {
+		t.Errorf("got=%v, want=%v", got, []string{"tcp", "ip"})
+	}
+
+	// the struct tags are those of encoding/xml, so the same struct reads both
+	type Motd struct {
+		Lang string `xml:"lang,attr"`
+		Text string `xml:",chardata"`
+	}
+	type Server struct {
+		XMLName xml.Name `xml:"server"`
+		Name    string   `xml:"name,attr"`
+		Host    string   `xml:"host"`
+		Port    int      `xml:"port"`
+		Motd    Motd     `xml:"motd"`
+	}
+	type Config struct {
+		XMLName xml.Name `xml:"config"`
+		Server  Server   `xml:"server"`
+		Depends []string `xml:"depend"`
+	}
+	var decoded, unmarshaled Config
+	if err := e.DecodeToStruct("config", &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := xml.Unmarshal([]byte(content), &unmarshaled); err != nil {
+		t.Fatal(err)
+	}
+	decoded.XMLName, decoded.Server.XMLName = unmarshaled.XMLName, unmarshaled.Server.XMLName
+	if !reflect.DeepEqual(decoded, unmarshaled) {
+		t.Errorf("got=%+v, want=%+v", decoded, unmarshaled)
+	}
+
+	e, _ = newTestEngine(t, ".xml", content, WithWeaklyTypedInput(true), WithXMLAttrPrefix("-"))
+	var server Server
+	if err := e.DecodeToStruct("config.server", &server); err != nil {
+		t.Fatal(err)
+	}
+	if server.Name != "http" || server.Motd.Lang != "en" {
+		t.Errorf("prefix -: got=%+v", server)
+	}
+}
+
+func TestWithFormatAutoFixedAtLoad(t *testing.T) {
+	e := NewEngine()
+	if err := e.LoadBytes([]byte("listen_port: 8080"), FormatAuto, WithLogLevel(1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Validate([]byte(`{"listen_port": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+	var server = &struct {
+		Port int `yaml:"listen_port"`
+	}{}
+	if err := e.DecodeToStruct("", server); err != nil {
+		t.Fatal(err)
+	}
+	if server.Port != 8080 {
+		t.Errorf("got=%d, want=%d", server.Port, 8080)
+	}
+}
@@ -5,9 +5,17 @@ go 1.19
 require (
 	github.com/BurntSushi/toml v1.2.1
 	github.com/fsnotify/fsnotify v1.6.0
+	github.com/hashicorp/hcl/v2 v2.16.2
 	github.com/mitchellh/mapstructure v1.5.0
 	github.com/spf13/cast v1.5.0
+	github.com/zclconf/go-cty v1.12.1
 	gopkg.in/yaml.v3 v3.0.1
 )
 
-require golang.org/x/sys v0.0.0-20220908164124-27713097b956 // indirect
+require (
+	github.com/agext/levenshtein v1.2.1 // indirect
+	github.com/apparentlymart/go-textseg/v13 v13.0.0 // indirect
+	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
+	golang.org/x/sys v0.0.0-20220908164124-27713097b956 // indirect
+	golang.org/x/text v0.3.7 // indirect
+)
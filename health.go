@@ -0,0 +1,16 @@
+package conf_reload
+
+import "time"
+
+// HealthState is the state of an Engine
+type HealthState struct {
+	Watching bool      // the broker is watching the config file for changes
+	Degraded bool      // changes may be missed until the watch recovers
+	Reason   string    // why the engine is degraded
+	Since    time.Time // when the watch state last changed
+
+	Paused       bool      // reloads are held by Pause
+	Pending      bool      // a change is held and will be applied once reloads are released
+	PendingSince time.Time // when the first held change was queued
+	HeldReason   string    // why the pending change is held
+}
@@ -0,0 +1,55 @@
+package conf_reload
+
+import (
+	"fmt"
+	"github.com/enpsl/conf-reload/internal/base"
+	"github.com/enpsl/conf-reload/internal/errors"
+	"strconv"
+	"time"
+)
+
+// HistoryEntry is an applied config kept for Rollback.
+// RawData and Configure are shared with the engine and must not be modified
+type HistoryEntry struct {
+	Revision  uint64                 // revision the config was applied as
+	RawData   []byte                 // config original data
+	Configure map[string]interface{} // parsed config
+	Time      time.Time              // when the config was applied
+	Source    string                 // what applied the config, e.g. "write /etc/app.toml"
+}
+
+// History returns the last HistorySize applied configs, oldest first
+func (e *Engine) History() []HistoryEntry {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]HistoryEntry{}, e.history...)
+}
+
+// Rollback
+// Re-apply the config of revision from the history as a new revision.
+// It only changes the config in memory, the next change of the file is applied as usual
+func (e *Engine) Rollback(revision uint64) error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, entry := range e.history {
+		if entry.Revision == revision {
+			e.commit(entry.RawData, entry.Configure, base.NewEvent(strconv.FormatUint(revision, 10), base.ReasonRollback))
+			e.Logger.Infof("rollback to revision %d as revision %d", revision, e.revision)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w :%d", errors.ErrRevisionNotFound, revision)
+}
+
+// record appends entry to the history, dropping the oldest beyond HistorySize
+func (e *Engine) record(entry HistoryEntry) {
+	if e.HistorySize <= 0 {
+		return
+	}
+	e.history = append(e.history, entry)
+	if over := len(e.history) - e.HistorySize; over > 0 {
+		e.history = append(e.history[:0:0], e.history[over:]...)
+	}
+}
@@ -0,0 +1,50 @@
+package conf_reload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestEngineHistoryRollback(t *testing.T) {
+	e, path := newTestEngine(t, ".toml", "port = 8080", WithHistorySize(3))
+	for port := 8081; port <= 8084; port++ {
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("port = %d", port)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Reload(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history := e.History()
+	if len(history) != 3 {
+		t.Fatalf("history size got=%d, want=%d", len(history), 3)
+	}
+	if history[0].Revision != 3 || history[2].Revision != 5 {
+		t.Errorf("history revisions got=%d..%d, want=%d..%d", history[0].Revision, history[2].Revision, 3, 5)
+	}
+
+	if err := e.Rollback(1); err == nil {
+		t.Error("Rollback to a dropped revision returned no error")
+	}
+	if err := e.Rollback(3); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetInt("port"); got != 8082 {
+		t.Errorf("got=%d, want=%d", got, 8082)
+	}
+	history = e.History()
+	if last := history[len(history)-1]; last.Revision != 6 || last.Source != "rollback 3" {
+		t.Errorf("got revision=%d source=%q, want revision=%d source=%q", last.Revision, last.Source, 6, "rollback 3")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "port = 8084" {
+		t.Errorf("Rollback touched the file: got=%s", content)
+	}
+}
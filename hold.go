@@ -0,0 +1,217 @@
+package conf_reload
+
+import (
+	"context"
+	"github.com/enpsl/conf-reload/internal/base"
+	"github.com/enpsl/conf-reload/internal/errors"
+	"time"
+)
+
+// ErrReloadHeld is returned by Reload while reloads are held,
+// the reload is queued and applied once reloads are released
+var ErrReloadHeld = errors.ErrReloadHeld
+
+// ReloadWindow is a daily time window in which reloads are applied
+type ReloadWindow struct {
+	Start time.Duration  // offset from local midnight the window opens
+	End   time.Duration  // offset from local midnight the window closes, less than Start spans midnight
+	Days  []time.Weekday // days the window opens, empty means every day
+}
+
+// contains reports whether t is inside the window, comparing wall clock times
+// so that the window keeps its local hours on DST days
+func (w ReloadWindow) contains(t time.Time) bool {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	if w.Start <= w.End {
+		return w.opensOn(midnight) && offset >= w.Start && offset < w.End
+	}
+	return (w.opensOn(midnight) && offset >= w.Start) ||
+		(w.opensOn(midnight.AddDate(0, 0, -1)) && offset < w.End)
+}
+
+// next returns the first opening of the window after t, zero if it never opens.
+// The opening is built from the wall clock time of Start, not an elapsed time since midnight,
+// which is off by the shift on DST days
+func (w ReloadWindow) next(t time.Time) time.Time {
+	for i := 0; i <= 7; i++ {
+		day := time.Date(t.Year(), t.Month(), t.Day()+i, 0, 0, 0, 0, t.Location())
+		open := time.Date(day.Year(), day.Month(), day.Day(), int(w.Start/time.Hour), int(w.Start%time.Hour/time.Minute),
+			int(w.Start%time.Minute/time.Second), int(w.Start%time.Second), t.Location())
+		if open.After(t) && w.opensOn(day) {
+			return open
+		}
+	}
+	return time.Time{}
+}
+
+func (w ReloadWindow) opensOn(day time.Time) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// Pause
+// Hold every reload until Resume, the latest change is queued
+func (e *Engine) Pause() {
+	e.holdMu.Lock()
+	defer e.holdMu.Unlock()
+	e.paused = true
+	e.Logger.Info("reload paused")
+}
+
+// Resume
+// Release reloads held by Pause and apply the queued change, if any and if reloads are not otherwise held
+func (e *Engine) Resume() error {
+	e.holdMu.Lock()
+	e.paused = false
+	e.holdMu.Unlock()
+	e.Logger.Info("reload resumed")
+	return e.release()
+}
+
+// hold
+// Report why event must be held, or "" if it can be applied now.
+// A held event is queued as pending, replacing any older pending event
+func (e *Engine) hold(event base.Event) string {
+	e.holdMu.Lock()
+	defer e.holdMu.Unlock()
+	now := time.Now()
+	reason := ""
+	switch {
+	case e.paused:
+		reason = "paused"
+	case !e.inReloadWindow(now):
+		reason = "outside reload window"
+		e.scheduleRelease(e.nextReloadWindow(now))
+	case !e.allowReload(now):
+		reason = "rate limited"
+		e.scheduleRelease(e.quietAt(now))
+	}
+	if reason == "" {
+		e.pending = nil
+		return ""
+	}
+	if e.pending == nil {
+		e.pendingSince = now
+	}
+	e.pending = &event
+	e.heldReason = reason
+	return reason
+}
+
+// release applies the pending event
+func (e *Engine) release() error {
+	e.holdMu.Lock()
+	pending := e.pending
+	e.holdMu.Unlock()
+	if pending == nil {
+		return nil
+	}
+	return e.reload(context.Background(), *pending)
+}
+
+// scheduleRelease applies the pending event at t, replacing any earlier schedule
+func (e *Engine) scheduleRelease(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	if e.releaseTimer != nil {
+		e.releaseTimer.Stop()
+	}
+	e.releaseTimer = time.AfterFunc(time.Until(t), func() {
+		if err := e.release(); err != nil && !errors.Is(err, ErrReloadHeld) {
+			e.Logger.Error(err)
+		}
+	})
+}
+
+func (e *Engine) inReloadWindow(t time.Time) bool {
+	if len(e.ReloadWindows) == 0 {
+		return true
+	}
+	for _, w := range e.ReloadWindows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) nextReloadWindow(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range e.ReloadWindows {
+		if open := w.next(t); !open.IsZero() && (next.IsZero() || open.Before(next)) {
+			next = open
+		}
+	}
+	return next
+}
+
+// allowReload reports whether the reload rate limiter has a token,
+// refilled by one every ReloadInterval up to ReloadBurst.
+// The token is only taken by chargeReload once the reload commits a revision.
+// Running out of tokens means the config is flapping, which is logged once until it settles
+func (e *Engine) allowReload(now time.Time) bool {
+	if e.ReloadInterval <= 0 {
+		return true
+	}
+	burst := e.refill(now)
+	if e.tokens >= 1 {
+		if e.flapping {
+			e.flapping = false
+			e.Logger.Info("config flapping settled, applying the latest change")
+		}
+		return true
+	}
+	if !e.flapping {
+		e.flapping = true
+		e.Logger.Warnf("config flapping: more than %d reloads per %s, holding the latest change until it settles",
+			int(burst), e.ReloadInterval)
+	}
+	e.lastHeld = now
+	return false
+}
+
+// chargeReload takes a token from the reload rate limiter for a committed revision
+func (e *Engine) chargeReload(now time.Time) {
+	if e.ReloadInterval <= 0 {
+		return
+	}
+	e.holdMu.Lock()
+	defer e.holdMu.Unlock()
+	e.refill(now)
+	e.tokens--
+}
+
+// refill adds the tokens earned since the last refill and returns the burst, e.holdMu must be held
+func (e *Engine) refill(now time.Time) float64 {
+	burst := float64(e.ReloadBurst)
+	if burst < 1 {
+		burst = 1
+	}
+	if e.lastRefill.IsZero() {
+		e.tokens = burst
+	} else if e.tokens += float64(now.Sub(e.lastRefill)) / float64(e.ReloadInterval); e.tokens > burst {
+		e.tokens = burst
+	}
+	e.lastRefill = now
+	return burst
+}
+
+// quietAt returns when a held change is applied: once a token is available
+// and no change was held for ReloadInterval
+func (e *Engine) quietAt(now time.Time) time.Time {
+	token := now.Add(time.Duration((1 - e.tokens) * float64(e.ReloadInterval)))
+	if quiet := e.lastHeld.Add(e.ReloadInterval); quiet.After(token) {
+		return quiet
+	}
+	return token
+}
@@ -0,0 +1,158 @@
+package conf_reload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEnginePauseResume(t *testing.T) {
+	e, path := newTestEngine(t, ".toml", "port = 8080")
+	e.Pause()
+	if err := os.WriteFile(path, []byte("port = 8081"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Reload(context.Background()); !errors.Is(err, ErrReloadHeld) {
+		t.Fatalf("got=%v, want=%v", err, ErrReloadHeld)
+	}
+	if got := e.GetInt("port"); got != 8080 {
+		t.Errorf("paused engine applied a change: got=%d, want=%d", got, 8080)
+	}
+	if h := e.Health(); !h.Paused || !h.Pending || h.HeldReason == "" {
+		t.Errorf("got=%+v, want paused and pending", h)
+	}
+
+	if err := e.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetInt("port"); got != 8081 {
+		t.Errorf("got=%d, want=%d", got, 8081)
+	}
+	if h := e.Health(); h.Paused || h.Pending {
+		t.Errorf("got=%+v, want neither paused nor pending", h)
+	}
+}
+
+func TestReloadWindow(t *testing.T) {
+	at := func(day, hour, min int) time.Time {
+		// 2023-02-19 is a Sunday
+		return time.Date(2023, 2, day, hour, min, 0, 0, time.Local)
+	}
+	tests := []struct {
+		desc     string
+		window   ReloadWindow
+		t        time.Time
+		contains bool
+		next     time.Time
+	}{
+		{
+			desc:     "inside daily window",
+			window:   ReloadWindow{Start: 9 * time.Hour, End: 17 * time.Hour},
+			t:        at(19, 12, 0),
+			contains: true,
+			next:     at(20, 9, 0),
+		},
+		{
+			desc:     "before daily window",
+			window:   ReloadWindow{Start: 9 * time.Hour, End: 17 * time.Hour},
+			t:        at(19, 8, 0),
+			contains: false,
+			next:     at(19, 9, 0),
+		},
+		{
+			desc:     "window spanning midnight",
+			window:   ReloadWindow{Start: 22 * time.Hour, End: 2 * time.Hour},
+			t:        at(20, 1, 0),
+			contains: true,
+			next:     at(20, 22, 0),
+		},
+		{
+			desc:     "weekday window on sunday",
+			window:   ReloadWindow{Start: 9 * time.Hour, End: 17 * time.Hour, Days: []time.Weekday{time.Monday}},
+			t:        at(19, 12, 0),
+			contains: false,
+			next:     at(20, 9, 0),
+		},
+	}
+	for _, tc := range tests {
+		if got := tc.window.contains(tc.t); got != tc.contains {
+			t.Errorf("%s: contains got=%t, want=%t", tc.desc, got, tc.contains)
+		}
+		if got := tc.window.next(tc.t); !got.Equal(tc.next) {
+			t.Errorf("%s: next got=%s, want=%s", tc.desc, got, tc.next)
+		}
+	}
+}
+
+func TestReloadWindowDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// clocks go forward from 02:00 to 03:00 on 2026-03-08 and back from 02:00 to 01:00 on 2026-11-01
+	window := ReloadWindow{Start: 9 * time.Hour, End: 17 * time.Hour}
+	for _, day := range []int{8, 1} {
+		month := time.March
+		if day == 1 {
+			month = time.November
+		}
+		midnight := time.Date(2026, month, day, 0, 0, 0, 0, loc)
+		want := time.Date(2026, month, day, 9, 0, 0, 0, loc)
+		if got := window.next(midnight); !got.Equal(want) {
+			t.Errorf("%s: next got=%s, want=%s", midnight.Format("2006-01-02"), got, want)
+		}
+		if window.contains(want.Add(-time.Minute)) || !window.contains(want) {
+			t.Errorf("%s: window does not open at %s", midnight.Format("2006-01-02"), want)
+		}
+	}
+}
+
+func TestEngineReloadRateLimit(t *testing.T) {
+	e, path := newTestEngine(t, ".toml", "port = 8000", WithReloadRateLimit(100*time.Millisecond, 2))
+	// reloads committing no revision take no token
+	for i := 0; i < 5; i++ {
+		if err := e.Reload(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, []byte("port = "), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Reload(context.Background()); err == nil || errors.Is(err, ErrReloadHeld) {
+		t.Fatalf("got=%v, want a parse error", err)
+	}
+	held := 0
+	for port := 8001; port <= 8010; port++ {
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("port = %d", port)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Reload(context.Background()); errors.Is(err, ErrReloadHeld) {
+			held++
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if held != 8 {
+		t.Errorf("held got=%d, want=%d", held, 8)
+	}
+	if got := e.GetInt("port"); got != 8002 {
+		t.Errorf("got=%d, want=%d", got, 8002)
+	}
+	if h := e.Health(); !h.Pending {
+		t.Errorf("got=%+v, want pending", h)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for e.GetInt("port") != 8010 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := e.GetInt("port"); got != 8010 {
+		t.Errorf("latest change was not applied once the storm passed: got=%d, want=%d", got, 8010)
+	}
+	if stats := e.Stats(); stats.Applied != 4 {
+		t.Errorf("applied got=%d, want=%d", stats.Applied, 4)
+	}
+}
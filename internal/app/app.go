@@ -4,6 +4,8 @@
 
 package app
 
+import "time"
+
 const PackageName = "conf-reload"
 
 const Version = "v1.0.0"
@@ -11,3 +13,13 @@ const Version = "v1.0.0"
 const DefaultLevelSplit = "."
 
 const DefaultCapacity = 100
+
+const DefaultXMLAttrPrefix = "@"
+
+const DefaultPollInterval = time.Second
+
+const DefaultRetryMinBackoff = 100 * time.Millisecond
+
+const DefaultRetryMaxBackoff = 30 * time.Second
+
+const DefaultHistorySize = 10
@@ -1,18 +1,43 @@
 // Copyright 2023 enpsl. All rights reserved.
 
-// Broker interface
+// Broker events and health, shared by the public Broker interface and its implementations
 
 package base
 
 import (
-	"io"
+	"time"
 )
 
-type Broker interface {
-	Parse(content []byte) (error, map[string]interface{})
-	LoadContent() ([]byte, error)
-	Watch()
-	Decode(input interface{}, output interface{}, weaklyTypedInput bool) error
-	Notify() <-chan struct{}
-	io.Closer
+// Health is the watch state of a broker
+type Health struct {
+	Watching bool      // the broker is watching for changes
+	Degraded bool      // changes may be missed until the watch recovers
+	Reason   string    // why the watch is degraded
+	Since    time.Time // when the state last changed
+}
+
+// EventReason is why an Event was sent
+type EventReason string
+
+const (
+	ReasonLoad          EventReason = "load"            // the engine loaded the config
+	ReasonWrite         EventReason = "write"           // the file was written or created
+	ReasonSymlink       EventReason = "symlink"         // a symlink on the path was swapped, e.g. ..data of a ConfigMap volume
+	ReasonPoll          EventReason = "poll"            // polling found a content change
+	ReasonRecover       EventReason = "recover"         // the watch recovered and changes may have been missed
+	ReasonManual        EventReason = "manual"          // Engine.Reload was called
+	ReasonSignal        EventReason = "signal"          // the process received a reload signal
+	ReasonRollback      EventReason = "rollback"        // Engine.Rollback re-applied an older config
+	ReasonLastKnownGood EventReason = "last-known-good" // the engine fell back to the last known good config
+)
+
+// Event is a change notification, sent by a broker or triggered on the engine
+type Event struct {
+	Name   string      // the file that changed
+	Reason EventReason // why the event was sent
+	Time   time.Time   // when the change was seen
+}
+
+func NewEvent(name string, reason EventReason) Event {
+	return Event{Name: name, Reason: reason, Time: time.Now()}
 }
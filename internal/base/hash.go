@@ -0,0 +1,28 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// content hash used to detect unchanged reloads
+
+package base
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+type Sum [sha256.Size]byte
+
+// SumBytes returns the hash of the raw content
+func SumBytes(content []byte) Sum {
+	return sha256.Sum256(content)
+}
+
+// SumMap returns the hash of a parsed config,
+// it does not depend on the key order or formatting of the raw content
+func SumMap(m map[string]interface{}) Sum {
+	b, err := json.Marshal(m)
+	if err != nil {
+		b = []byte(fmt.Sprintf("%#v", m))
+	}
+	return sha256.Sum256(b)
+}
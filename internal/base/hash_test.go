@@ -0,0 +1,35 @@
+package base
+
+import "testing"
+
+func TestSumMap(t *testing.T) {
+	tests := []struct {
+		desc string
+		a, b map[string]interface{}
+		want bool
+	}{
+		{
+			desc: "same content",
+			a:    map[string]interface{}{"host": "0.0.0.0", "port": 8080},
+			b:    map[string]interface{}{"port": 8080, "host": "0.0.0.0"},
+			want: true,
+		},
+		{
+			desc: "nested value changed",
+			a:    map[string]interface{}{"http": map[string]interface{}{"port": 8080}},
+			b:    map[string]interface{}{"http": map[string]interface{}{"port": 8081}},
+			want: false,
+		},
+		{
+			desc: "non string keys",
+			a:    map[string]interface{}{"m": map[interface{}]interface{}{1: "a"}},
+			b:    map[string]interface{}{"m": map[interface{}]interface{}{1: "a"}},
+			want: true,
+		},
+	}
+	for _, tc := range tests {
+		if got := SumMap(tc.a) == SumMap(tc.b); got != tc.want {
+			t.Errorf("%s: got=%t, want=%t", tc.desc, got, tc.want)
+		}
+	}
+}
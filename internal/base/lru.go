@@ -97,6 +97,8 @@ func (this *LRUCache) MoveToHead(node *DoubleLink) {
 }
 
 func (this *LRUCache) Flush() {
+	this.mu.Lock()
+	defer this.mu.Unlock()
 	this.hashMap = make(map[string]*DoubleLink, this.capacity)
 	this.size = 0
 	this.head = initNode()
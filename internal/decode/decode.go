@@ -0,0 +1,42 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// Package decode decodes a config map into structs based on mapstructure
+
+package decode
+
+import (
+	"fmt"
+	"github.com/enpsl/conf-reload/internal/errors"
+	"github.com/mitchellh/mapstructure"
+)
+
+// Decoder decodes with mapstructure, reading field names from TagName tags
+type Decoder struct {
+	TagName string
+	Hook    mapstructure.DecodeHookFunc // runs after the string to duration hook, may be nil
+}
+
+func New(tagName string) *Decoder {
+	return &Decoder{TagName: tagName}
+}
+
+func (d *Decoder) Decode(input interface{}, output interface{}, weaklyTypedInput bool) error {
+	config := mapstructure.DecoderConfig{
+		DecodeHook:       d.hook(),
+		Result:           output,
+		TagName:          d.TagName,
+		WeaklyTypedInput: weaklyTypedInput,
+	}
+	decoder, err := mapstructure.NewDecoder(&config)
+	if err != nil {
+		return errors.ErrFormat(errors.ErrBrokerDecode, fmt.Errorf("decode err %w", err))
+	}
+	return decoder.Decode(input)
+}
+
+func (d *Decoder) hook() mapstructure.DecodeHookFunc {
+	if d.Hook == nil {
+		return mapstructure.StringToTimeDurationHookFunc()
+	}
+	return mapstructure.ComposeDecodeHookFunc(mapstructure.StringToTimeDurationHookFunc(), d.Hook)
+}
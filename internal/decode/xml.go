@@ -0,0 +1,91 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// decoding of xml configs through encoding/xml struct tags
+
+package decode
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+)
+
+var xmlNameType = reflect.TypeOf(xml.Name{})
+
+// NewXml returns a Decoder reading the xml struct tags the way encoding/xml does:
+// a `xml:"name,attr"` field is the attribute keyed by attrPrefix and name,
+// a `xml:",chardata"` field is the text keyed by textKey, other fields are child elements
+// and XMLName fields are skipped
+func NewXml(attrPrefix, textKey string) *Decoder {
+	return &Decoder{TagName: "xml", Hook: xmlHook(attrPrefix, textKey)}
+}
+
+// xmlHook rekeys the map of an xml element decoded into a struct by the fields of the struct
+func xmlHook(attrPrefix, textKey string) func(from, to reflect.Type, data interface{}) (interface{}, error) {
+	return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		if to.Kind() != reflect.Struct {
+			return data, nil
+		}
+		var m map[string]interface{}
+		switch v := data.(type) {
+		case map[string]interface{}:
+			m = v
+		case string:
+			// an element with neither attributes nor child elements is its text
+			m = map[string]interface{}{textKey: v}
+		default:
+			return data, nil
+		}
+		out := make(map[string]interface{}, len(m))
+		for i := 0; i < to.NumField(); i++ {
+			field := to.Field(i)
+			if field.Type == xmlNameType || field.PkgPath != "" {
+				continue
+			}
+			tag := field.Tag.Get("xml")
+			if tag == "-" {
+				continue
+			}
+			name, opts := tag, ""
+			if i := strings.IndexByte(tag, ','); i >= 0 {
+				name, opts = tag[:i], tag[i+1:]
+			}
+			if name == "" {
+				name = field.Name
+			}
+			key := name
+			switch {
+			case xmlOption(opts, "attr"):
+				key = attrPrefix + name
+			case xmlOption(opts, "chardata"):
+				key = textKey
+			}
+			if v, ok := xmlLookup(m, key); ok {
+				out[name] = v
+			}
+		}
+		return out, nil
+	}
+}
+
+func xmlOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// xmlLookup returns the value of key in m, matching the key case insensitively like field names
+func xmlLookup(m map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
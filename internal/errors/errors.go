@@ -30,10 +30,26 @@ var (
 	ErrInvalidFileExt ErrType = errors.New("invalid file ext type")
 	// ErrUnmarshaller indicates that we can't unmarshal content
 	ErrUnmarshaller ErrType = errors.New("unmarshal error")
+	// ErrMarshaller indicates that we can't marshal a config
+	ErrMarshaller ErrType = errors.New("marshal error")
+	// ErrInvalidFormat indicates that a format can't be registered or is not registered
+	ErrInvalidFormat ErrType = errors.New("invalid format")
+	// ErrAmbiguousFormat indicates that the format of content can't be told apart
+	ErrAmbiguousFormat ErrType = errors.New("ambiguous config format")
 	// ErrInvalidKey indicates that we can't get valid key
 	ErrInvalidKey ErrType = errors.New("key is invalid")
 	// ErrBrokerDecode indicates that broker can't decode content
 	ErrBrokerDecode ErrType = errors.New("broker can not decode")
+	// ErrConfigNotFound indicates that no config file was found in the search paths
+	ErrConfigNotFound ErrType = errors.New("config file not found")
+	// ErrValidation indicates that a validator rejected the config
+	ErrValidation ErrType = errors.New("config validation failed")
+	// ErrReloadHeld indicates that a reload is held and queued until reloads are released
+	ErrReloadHeld ErrType = errors.New("reload held")
+	// ErrNotLoaded indicates that the engine has no config loaded yet
+	ErrNotLoaded ErrType = errors.New("config not loaded")
+	// ErrRevisionNotFound indicates that the revision is not in the history
+	ErrRevisionNotFound ErrType = errors.New("revision not found in history")
 )
 
 /***************************************************************
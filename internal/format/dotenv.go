@@ -0,0 +1,231 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// dotenv unmarshaller and marshaller
+
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// dotenvFormat parses .env content: `KEY=value` lines with an optional `export ` prefix and # comments.
+// Unquoted values end at a # comment preceded by whitespace, single quoted values are literal,
+// double quoted values may span lines and read the escapes \n \r \t \" \\ \$.
+// $VAR, ${VAR} and ${VAR:-default} expand to a key set above or to the environment, except in single quotes.
+// Keys are kept flat, or split on opts.EnvSeparator and lowercased into nested keys
+func dotenvFormat(opts Options) (Unmarshaller, Marshaller) {
+	return func(content []byte, v interface{}) error {
+			return unmarshalDotenv(content, v, opts.EnvSeparator)
+		}, func(v interface{}) ([]byte, error) {
+			return marshalDotenv(v, opts.EnvSeparator)
+		}
+}
+
+func unmarshalDotenv(content []byte, v interface{}, separator string) error {
+	config, err := configMap(v)
+	if err != nil {
+		return err
+	}
+	vars := make(map[string]string)
+	lookup := func(name string) (string, bool) {
+		if value, ok := vars[name]; ok {
+			return value, true
+		}
+		return os.LookupEnv(name)
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if rest := strings.TrimPrefix(line, "export"); rest != line && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimSpace(rest)
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return fmt.Errorf("line %d: expected KEY=value, got %s", lineNo, line)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if !validEnvKey(key) {
+			return fmt.Errorf("line %d: invalid key %q", lineNo, key)
+		}
+
+		var value string
+		rest := strings.TrimLeft(line[eq+1:], " \t")
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			quote, body := rest[0], rest[1:]
+			end := closingQuote(body, quote)
+			for end < 0 && i+1 < len(lines) {
+				i++
+				body += "\n" + lines[i]
+				end = closingQuote(body, quote)
+			}
+			if end < 0 {
+				return fmt.Errorf("line %d: unterminated quoted value of %s", lineNo, key)
+			}
+			if tail := strings.TrimSpace(body[end+1:]); tail != "" && tail[0] != '#' {
+				return fmt.Errorf("line %d: unexpected %s after quoted value of %s", lineNo, tail, key)
+			}
+			value = body[:end]
+			if quote == '"' {
+				value, err = expandEnv(value, true, lookup)
+			}
+		} else {
+			for j := 1; j < len(rest); j++ {
+				if rest[j] == '#' && (rest[j-1] == ' ' || rest[j-1] == '\t') {
+					rest = rest[:j]
+					break
+				}
+			}
+			value, err = expandEnv(strings.TrimSpace(rest), false, lookup)
+		}
+		if err == nil {
+			vars[key] = value
+			err = nest(config, envKey(key, separator), strings.ToLower(separator), value)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	return nil
+}
+
+func validEnvKey(key string) bool {
+	if key == "" || (key[0] >= '0' && key[0] <= '9') {
+		return false
+	}
+	for _, c := range key {
+		if !isEnvNameChar(c) && c != '.' && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func isEnvNameChar(c rune) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// envKey lowercases key to be nested on separator, keys are kept as is when flat
+func envKey(key, separator string) string {
+	if separator == "" {
+		return key
+	}
+	return strings.ToLower(key)
+}
+
+// closingQuote returns the index of the quote closing s, -1 if there is none.
+// A double quote escaped by a backslash does not close s
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// expandEnv expands the variables of s, and its escapes when escapes is set.
+// \$ is a literal $ either way
+func expandEnv(s string, escapes bool, lookup func(string) (string, bool)) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && (escapes || s[i+1] == '$'):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		case c == '$':
+			value, n, err := expandVar(s[i+1:], lookup)
+			if err != nil {
+				return "", err
+			}
+			if n == 0 {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteString(value)
+			i += n
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// expandVar expands the variable s starts with, following a $,
+// and returns the number of bytes it spans, 0 if s does not start with one
+func expandVar(s string, lookup func(string) (string, bool)) (string, int, error) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", 0, fmt.Errorf("unterminated ${ in %s", s)
+		}
+		name, fallback, hasDefault := strings.Cut(s[1:end], ":-")
+		if !validEnvKey(name) {
+			return "", 0, fmt.Errorf("invalid variable ${%s}", s[1:end])
+		}
+		value, ok := lookup(name)
+		if hasDefault && (!ok || value == "") {
+			value = fallback
+		}
+		return value, end + 1, nil
+	}
+	n := 0
+	for n < len(s) && isEnvNameChar(rune(s[n])) && !(n == 0 && s[n] >= '0' && s[n] <= '9') {
+		n++
+	}
+	if n == 0 {
+		return "", 0, nil
+	}
+	value, _ := lookup(s[:n])
+	return value, n, nil
+}
+
+var dotenvEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+func marshalDotenv(v interface{}, separator string) ([]byte, error) {
+	config, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("dotenv can not marshal %T", v)
+	}
+	var buf bytes.Buffer
+	for _, l := range flat(config, "", "\x00") {
+		key := l.key
+		if strings.Contains(key, "\x00") {
+			if separator == "" {
+				return nil, fmt.Errorf("can not marshal nested key %s without a separator", strings.ReplaceAll(key, "\x00", "."))
+			}
+			key = strings.ToUpper(strings.ReplaceAll(key, "\x00", separator))
+		} else if separator != "" {
+			key = strings.ToUpper(key)
+		}
+		s, err := scalar(key, l.value)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, "%s=\"%s\"\n", key, dotenvEscaper.Replace(s))
+	}
+	return buf.Bytes(), nil
+}
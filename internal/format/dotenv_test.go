@@ -0,0 +1,112 @@
+package format
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDotenvParse(t *testing.T) {
+	t.Setenv("DOTENV_TEST_HOME", "/home/app")
+	content := `# comment
+export SERVER__HTTP__HOST=0.0.0.0
+SERVER__HTTP__PORT = 8080 # inline comment
+SERVER__NAME='literal ${HOME} \n'
+SERVER__MOTD="first line
+second \"line\""
+DATA_DIR=${DOTENV_TEST_HOME}/data
+LOG_DIR="$DATA_DIR/log"
+LEVEL=${DOTENV_TEST_UNSET:-info}
+PRICE=\$5
+COLOR=#fff
+`
+	tests := []struct {
+		separator string
+		want      map[string]interface{}
+	}{
+		{
+			separator: "",
+			want: map[string]interface{}{
+				"SERVER__HTTP__HOST": "0.0.0.0",
+				"SERVER__HTTP__PORT": "8080",
+				"SERVER__NAME":       `literal ${HOME} \n`,
+				"SERVER__MOTD":       "first line\nsecond \"line\"",
+				"DATA_DIR":           "/home/app/data",
+				"LOG_DIR":            "/home/app/data/log",
+				"LEVEL":              "info",
+				"PRICE":              "$5",
+				"COLOR":              "#fff",
+			},
+		},
+		{
+			separator: "__",
+			want: map[string]interface{}{
+				"server": map[string]interface{}{
+					"http": map[string]interface{}{"host": "0.0.0.0", "port": "8080"},
+					"name": `literal ${HOME} \n`,
+					"motd": "first line\nsecond \"line\"",
+				},
+				"data_dir": "/home/app/data",
+				"log_dir":  "/home/app/data/log",
+				"level":    "info",
+				"price":    "$5",
+				"color":    "#fff",
+			},
+		},
+	}
+	_, f := New(".env")
+	for _, tc := range tests {
+		err, m := f.WithOptions(Options{EnvSeparator: tc.separator}).Parse([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, tc.want) {
+			t.Errorf("separator %q: got=%v, want=%v", tc.separator, m, tc.want)
+		}
+	}
+}
+
+func TestDotenvParseError(t *testing.T) {
+	tests := []struct {
+		content string
+		line    string
+	}{
+		{content: "A=1\nB", line: "line 2"},
+		{content: "A=1\n1A=2", line: "line 2"},
+		{content: "A=\"open\nB=2", line: "line 1"},
+		{content: "A=\"x\" y", line: "line 1"},
+		{content: "A=${B", line: "line 1"},
+	}
+	_, f := New(".env")
+	for _, tc := range tests {
+		err, _ := f.Parse([]byte(tc.content))
+		if err == nil || !strings.Contains(err.Error(), tc.line) {
+			t.Errorf("%q: got=%v, want error at %s", tc.content, err, tc.line)
+		}
+	}
+}
+
+func TestDotenvRoundTrip(t *testing.T) {
+	tests := []struct {
+		separator string
+		config    map[string]interface{}
+	}{
+		{separator: "", config: map[string]interface{}{"PATH": `C:\app $HOME "x"`, "MOTD": "a\nb"}},
+		{separator: "__", config: map[string]interface{}{"server": map[string]interface{}{"port": "8080"}}},
+	}
+	_, f := New(".env")
+	for _, tc := range tests {
+		f := f.WithOptions(Options{EnvSeparator: tc.separator})
+		err, content := f.Marshal(tc.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err, m := f.Parse(content)
+		if err != nil {
+			t.Fatalf("%v\n%s", err, content)
+		}
+		if !reflect.DeepEqual(m, tc.config) {
+			t.Errorf("separator %q: got=%v, want=%v\n%s", tc.separator, m, tc.config, content)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// Package format parses config content into a config map,
+// one Format per registered file ext.
+
+package format
+
+import (
+	"fmt"
+	"github.com/enpsl/conf-reload/internal/decode"
+	"github.com/enpsl/conf-reload/internal/errors"
+	"path/filepath"
+)
+
+type FileExtType string
+
+const FileExtToml FileExtType = "toml"
+const FileExtJson FileExtType = "json"
+const FileExtYaml FileExtType = "yaml"
+const FileExtHcl FileExtType = "hcl"
+const FileExtIni FileExtType = "ini"
+const FileExtProperties FileExtType = "properties"
+const FileExtDotenv FileExtType = "dotenv"
+const FileExtJsonc FileExtType = "jsonc"
+const FileExtJson5 FileExtType = "json5"
+const FileExtXml FileExtType = "xml"
+
+// Auto is the name of the Format detecting the format of the content it parses,
+// every Parse detects it again, the engine fixes it to the format detected at Load
+const Auto = "auto"
+
+// Format parses content with the unmarshaller registered for its FileExtType
+type Format struct {
+	ext          FileExtType
+	tag          string // struct tag name read by Decode
+	unmarshaller Unmarshaller
+	marshaller   Marshaller
+	configurable configurable // builds the unmarshaller and marshaller configured by Options
+	decoding     decoding     // builds the decoder configured by Options, nil decodes with tag
+	decoder      *decode.Decoder
+}
+
+// New returns the Format registered for the ext of file
+func New(file string) (error, *Format) {
+	ext := ExtParser(file)
+	f, ok := lookup(ext)
+	if !ok {
+		return errors.ErrFormat(errors.ErrInvalidFileExt, errors.New("ext is unsupport")), nil
+	}
+	return nil, f
+}
+
+// Named returns the Format registered as name whatever the file ext,
+// or a Format sniffing the content for Auto
+func Named(name string) (error, *Format) {
+	if name == Auto {
+		return nil, &Format{ext: Auto, tag: Auto}
+	}
+	f, ok := lookup(FileExtType(name))
+	if !ok {
+		return fmt.Errorf("%w: %s is not registered", errors.ErrInvalidFormat, name), nil
+	}
+	return nil, f
+}
+
+// ExtParser returns the FileExtType registered for the ext of file, empty if there is none
+func ExtParser(file string) FileExtType {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	return registry.exts[filepath.Ext(file)]
+}
+
+// WithOptions returns the format configured by opts, such as ini nesting its keys on opts.LevelSplit,
+// formats without options are returned as is
+func (f *Format) WithOptions(opts Options) *Format {
+	if f.configurable == nil {
+		return f
+	}
+	unmarshal, marshal := f.configurable(opts)
+	configured := &Format{ext: f.ext, tag: f.tag, unmarshaller: unmarshal, marshaller: marshal,
+		configurable: f.configurable, decoding: f.decoding}
+	if f.decoding != nil {
+		configured.decoder = f.decoding(opts)
+	}
+	return configured
+}
+
+// Name returns the FileExtType of the format
+func (f *Format) Name() string {
+	return string(f.ext)
+}
+
+// Detect returns the format of content for the Auto format, f itself otherwise.
+// Nothing is stored, content is detected anew on every call
+func (f *Format) Detect(content []byte) (error, *Format) {
+	if f.ext != Auto {
+		return nil, f
+	}
+	return sniff(content)
+}
+
+// Parse parses content into a config map, the Auto format parses it with the format it detects
+func (f *Format) Parse(content []byte) (error, map[string]interface{}) {
+	if f.ext == Auto {
+		err, d := sniff(content)
+		if err != nil {
+			return err, nil
+		}
+		return d.Parse(content)
+	}
+	var config = make(map[string]interface{})
+	err := f.unmarshaller(content, &config)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errors.ErrUnmarshaller, err), nil
+	}
+	return nil, config
+}
+
+// Marshal encodes config into content Parse reads back
+func (f *Format) Marshal(config map[string]interface{}) (error, []byte) {
+	if f.marshaller == nil {
+		return errors.ErrFormat(errors.ErrMarshaller, errors.New("format "+f.Name()+" can not marshal")), nil
+	}
+	content, err := f.marshaller(config)
+	if err != nil {
+		return errors.ErrFormat(errors.ErrMarshaller, err), nil
+	}
+	return nil, content
+}
+
+// Decode decodes input into output, reading field names from the struct tags of the format,
+// named after the format but for the json dialects reading json tags.
+// xml reads the xml tags as encoding/xml does, `xml:"name,attr"` being an attribute
+func (f *Format) Decode(input interface{}, output interface{}, weaklyTypedInput bool) error {
+	if f.decoder != nil {
+		return f.decoder.Decode(input, output, weaklyTypedInput)
+	}
+	return decode.New(f.tag).Decode(input, output, weaklyTypedInput)
+}
@@ -0,0 +1,97 @@
+package format
+
+import (
+	"github.com/enpsl/conf-reload/internal/errors"
+	yaml "gopkg.in/yaml.v3"
+	"testing"
+)
+
+func TestFormatParse(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+		want    interface{}
+	}{
+		{file: "app.toml", content: "port = 8080", want: int64(8080)},
+		{file: "app.json", content: `{"port": 8080}`, want: float64(8080)},
+		{file: "app.yml", content: "port: 8080", want: 8080},
+	}
+	for _, tc := range tests {
+		err, f := New(tc.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err, m := f.Parse([]byte(tc.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m["port"] != tc.want {
+			t.Errorf("%s: got=%v(%T), want=%v(%T)", tc.file, m["port"], m["port"], tc.want, tc.want)
+		}
+	}
+}
+
+func TestFormatUnsupportedExt(t *testing.T) {
+	if err, _ := New("app.conf"); !errors.Is(err, errors.ErrInvalidFileExt) {
+		t.Errorf("got=%v, want=%v", err, errors.ErrInvalidFileExt)
+	}
+}
+
+func TestRegisterOverride(t *testing.T) {
+	t.Cleanup(Snapshot())
+	upper := func(content []byte, v interface{}) error {
+		(*v.(*map[string]interface{}))["port"] = "overridden"
+		return nil
+	}
+	if err := Register("custom", []string{".yml"}, upper, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ExtParser("app.yml"); got != "custom" {
+		t.Errorf("got=%s, want=%s", got, "custom")
+	}
+	if got := ExtParser("app.yaml"); got != FileExtYaml {
+		t.Errorf("got=%s, want=%s", got, FileExtYaml)
+	}
+	_, f := New("app.yml")
+	if _, m := f.Parse([]byte("port: 8080")); m["port"] != "overridden" {
+		t.Errorf("got=%v, want=%v", m["port"], "overridden")
+	}
+	if err, _ := f.Marshal(map[string]interface{}{}); !errors.Is(err, errors.ErrMarshaller) {
+		t.Errorf("got=%v, want=%v", err, errors.ErrMarshaller)
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	config := map[string]interface{}{"server": map[string]interface{}{"host": "0.0.0.0"}}
+	for _, file := range []string{"app.toml", "app.json", "app.yaml"} {
+		_, f := New(file)
+		err, content := f.Marshal(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, m := f.Parse(content)
+		if host := m["server"].(map[string]interface{})["host"]; host != "0.0.0.0" {
+			t.Errorf("%s: got=%v, want=%v", file, host, "0.0.0.0")
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	restore := Snapshot()
+	if err := Register("custom", []string{".yml", ".custom"}, yaml.Unmarshal, nil); err != nil {
+		t.Fatal(err)
+	}
+	restore()
+	if got := ExtParser("app.yml"); got != FileExtYaml {
+		t.Errorf("got=%s, want=%s", got, FileExtYaml)
+	}
+	if err, _ := Named("custom"); err == nil {
+		t.Error("custom format still registered")
+	}
+	for _, ext := range Exts() {
+		if ext == ".custom" {
+			t.Error("custom ext still registered")
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// HCL2 unmarshaller
+
+package format
+
+import (
+	"fmt"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"math/big"
+)
+
+// unmarshalHcl parses HCL2 content into the config map pointed to by v.
+// Attributes map to values, blocks map to nested maps keyed by the block type
+// followed by its labels, so `server "http" { port = 8080 }` is server.http.port.
+// Blocks of the same keys repeated map to a slice, blocks of one type with a different number of labels,
+// such as `server "http" {}` and `server {}`, are an error
+func unmarshalHcl(content []byte, v interface{}) error {
+	config, err := configMap(v)
+	if err != nil {
+		return err
+	}
+	file, diags := hclsyntax.ParseConfig(content, "config.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		return diags
+	}
+	m, err := hclBody(file.Body.(*hclsyntax.Body))
+	if err != nil {
+		return err
+	}
+	for key, value := range m {
+		config[key] = value
+	}
+	return nil
+}
+
+func hclBody(body *hclsyntax.Body) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(body.Attributes)+len(body.Blocks))
+	for name, attr := range body.Attributes {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		v, err := ctyValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", attr.SrcRange, err)
+		}
+		m[name] = v
+	}
+	labels := make(map[string]int, len(body.Blocks))
+	for _, block := range body.Blocks {
+		if n, ok := labels[block.Type]; ok && n != len(block.Labels) {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Inconsistent block labels",
+				Detail: fmt.Sprintf("%s blocks have %d labels here and %d labels before, they can not be merged",
+					block.Type, len(block.Labels), n),
+				Subject: block.DefRange().Ptr(),
+			}}
+		}
+		labels[block.Type] = len(block.Labels)
+		value, err := hclBody(block.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err := hclBlock(m, append([]string{block.Type}, block.Labels...), value); err != nil {
+			return nil, fmt.Errorf("%s: %w", block.TypeRange, err)
+		}
+	}
+	return m, nil
+}
+
+// hclBlock sets the body of a block at the path of keys in m
+func hclBlock(m map[string]interface{}, keys []string, body map[string]interface{}) error {
+	for _, key := range keys[:len(keys)-1] {
+		switch next := m[key].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			m[key] = child
+			m = child
+		case map[string]interface{}:
+			m = next
+		default:
+			return fmt.Errorf("block %s conflicts with attribute %s", keys, key)
+		}
+	}
+	key := keys[len(keys)-1]
+	switch prev := m[key].(type) {
+	case nil:
+		m[key] = body
+	case map[string]interface{}:
+		m[key] = []interface{}{prev, body}
+	case []interface{}:
+		m[key] = append(prev, body)
+	default:
+		return fmt.Errorf("block %s conflicts with attribute %s", keys, key)
+	}
+	return nil
+}
+
+// ctyValue converts an evaluated expression to the values the other formats produce
+func ctyValue(v cty.Value) (interface{}, error) {
+	if v.IsNull() {
+		return nil, nil
+	}
+	if !v.IsWhollyKnown() {
+		return nil, fmt.Errorf("value is unknown")
+	}
+	t := v.Type()
+	switch {
+	case t == cty.String:
+		return v.AsString(), nil
+	case t == cty.Bool:
+		return v.True(), nil
+	case t == cty.Number:
+		bf := v.AsBigFloat()
+		if i, accuracy := bf.Int64(); accuracy == big.Exact {
+			return i, nil
+		}
+		f, _ := bf.Float64()
+		return f, nil
+	case t.IsListType() || t.IsTupleType() || t.IsSetType():
+		s := make([]interface{}, 0, v.LengthInt())
+		for it := v.ElementIterator(); it.Next(); {
+			_, elem := it.Element()
+			value, err := ctyValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, value)
+		}
+		return s, nil
+	case t.IsMapType() || t.IsObjectType():
+		m := make(map[string]interface{}, v.LengthInt())
+		for it := v.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			value, err := ctyValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			m[key.AsString()] = value
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %s", t.FriendlyName())
+}
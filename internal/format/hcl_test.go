@@ -0,0 +1,62 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHclParse(t *testing.T) {
+	content := `
+name = "app"
+ratio = 0.5
+tags = ["a", "b"]
+
+server "http" {
+  host = "0.0.0.0"
+  port = 8080
+}
+
+server "grpc" {
+  port = 9090
+}
+
+listener {
+  port = 1
+}
+
+listener {
+  port = 2
+}
+`
+	_, f := New("app.hcl")
+	err, m := f.Parse([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name":  "app",
+		"ratio": 0.5,
+		"tags":  []interface{}{"a", "b"},
+		"server": map[string]interface{}{
+			"http": map[string]interface{}{"host": "0.0.0.0", "port": int64(8080)},
+			"grpc": map[string]interface{}{"port": int64(9090)},
+		},
+		"listener": []interface{}{
+			map[string]interface{}{"port": int64(1)},
+			map[string]interface{}{"port": int64(2)},
+		},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got=%v, want=%v", m, want)
+	}
+}
+
+func TestHclParseError(t *testing.T) {
+	_, f := New("app.hcl")
+	for _, content := range []string{"port = ", "port = var.port", "server = 1\nserver \"http\" {}",
+		"server \"http\" {}\nserver {}", "server {}\nserver \"http\" {}"} {
+		if err, _ := f.Parse([]byte(content)); err == nil {
+			t.Errorf("%q parsed", content)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// INI unmarshaller and marshaller
+
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// iniFormat parses INI content: `key = value` or `key: value` pairs under [section] headers,
+// with ; and # comment lines. Sections and keys are nested on opts.LevelSplit, values are strings.
+// A value continues on the following lines indented deeper than its key,
+// a double quoted value reads the escapes \\ \" \n \r \t
+func iniFormat(opts Options) (Unmarshaller, Marshaller) {
+	return func(content []byte, v interface{}) error {
+			return unmarshalIni(content, v, opts.LevelSplit)
+		}, func(v interface{}) ([]byte, error) {
+			return marshalIni(v, opts.LevelSplit)
+		}
+}
+
+func unmarshalIni(content []byte, v interface{}, split string) error {
+	config, err := configMap(v)
+	if err != nil {
+		return err
+	}
+	var section, key string
+	var value []string
+	var keyLine, keyIndent int
+	flush := func() error {
+		if key == "" {
+			return nil
+		}
+		defer func() { key, value = "", nil }()
+		s, err := iniValue(strings.Join(value, "\n"))
+		if err == nil {
+			err = nest(config, section+key, split, s)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", keyLine, err)
+		}
+		return nil
+	}
+
+	for i, line := range strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if key != "" && trimmed != "" && indent > keyIndent {
+			value = append(value, trimmed)
+			continue
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		switch {
+		case trimmed == "" || trimmed[0] == ';' || trimmed[0] == '#':
+		case trimmed[0] == '[':
+			name := strings.TrimSpace(strings.TrimSuffix(trimmed[1:], "]"))
+			if !strings.HasSuffix(trimmed, "]") || name == "" {
+				return fmt.Errorf("line %d: invalid section %s", i+1, trimmed)
+			}
+			if _, err := nestMap(config, name, split); err != nil {
+				return fmt.Errorf("line %d: %w", i+1, err)
+			}
+			section = name + split
+		default:
+			sep := strings.IndexAny(trimmed, "=:")
+			if sep <= 0 {
+				return fmt.Errorf("line %d: expected key = value, got %s", i+1, trimmed)
+			}
+			key = strings.TrimSpace(trimmed[:sep])
+			value = []string{strings.TrimSpace(trimmed[sep+1:])}
+			keyLine, keyIndent = i+1, indent
+		}
+	}
+	return flush()
+}
+
+// iniValue unquotes a quoted value
+func iniValue(raw string) (string, error) {
+	if raw == "" || (raw[0] != '"' && raw[0] != '\'') {
+		return raw, nil
+	}
+	if len(raw) < 2 || raw[len(raw)-1] != raw[0] {
+		return "", fmt.Errorf("unterminated quoted value %s", raw)
+	}
+	if raw[0] == '\'' {
+		return raw[1 : len(raw)-1], nil
+	}
+	return iniUnescaper.Replace(raw[1 : len(raw)-1]), nil
+}
+
+var iniUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\r`, "\r", `\t`, "\t")
+var iniEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+func marshalIni(v interface{}, split string) ([]byte, error) {
+	config, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("ini can not marshal %T", v)
+	}
+	var buf bytes.Buffer
+	if err := writeIniSection(&buf, "", config, split); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeIniSection writes the values of m under the section name, then its nested sections
+func writeIniSection(buf *bytes.Buffer, name string, m map[string]interface{}, split string) error {
+	if name != "" {
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(buf, "[%s]\n", name)
+	}
+	var sections []string
+	for _, key := range sortedKeys(m) {
+		if _, ok := m[key].(map[string]interface{}); ok {
+			sections = append(sections, key)
+			continue
+		}
+		s, err := scalar(key, m[key])
+		if err != nil {
+			return err
+		}
+		if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s, "\n\r\t") || s[0] == '"' || s[0] == '\'' {
+			s = `"` + iniEscaper.Replace(s) + `"`
+		}
+		fmt.Fprintf(buf, "%s = %s\n", key, s)
+	}
+	for _, key := range sections {
+		path := key
+		if name != "" {
+			path = name + split + key
+		}
+		if err := writeIniSection(buf, path, m[key].(map[string]interface{}), split); err != nil {
+			return err
+		}
+	}
+	return nil
+}
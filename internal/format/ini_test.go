@@ -0,0 +1,184 @@
+package format
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIniParse(t *testing.T) {
+	content := `; global values
+name = app
+path: "C:\\conf\t\"x\""
+
+[server.http]
+host = 0.0.0.0
+port = 8080
+motd = first line
+  second line
+
+# single quotes are kept literally
+[log]
+format = '%s\n'
+`
+	_, f := New("app.ini")
+	err, m := f.Parse([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name": "app",
+		"path": "C:\\conf\t\"x\"",
+		"server": map[string]interface{}{
+			"http": map[string]interface{}{"host": "0.0.0.0", "port": "8080", "motd": "first line\nsecond line"},
+		},
+		"log": map[string]interface{}{"format": `%s\n`},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got=%v, want=%v", m, want)
+	}
+}
+
+func TestIniLevelSplit(t *testing.T) {
+	_, f := New("app.ini")
+	err, m := f.WithOptions(Options{LevelSplit: "/"}).Parse([]byte("[server/http]\nlimit.rate = 10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"server": map[string]interface{}{"http": map[string]interface{}{"limit.rate": "10"}},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got=%v, want=%v", m, want)
+	}
+}
+
+func TestIniParseError(t *testing.T) {
+	tests := []struct {
+		content string
+		line    string
+	}{
+		{content: "[server", line: "line 1"},
+		{content: "name = app\njust a line", line: "line 2"},
+		{content: "server = x\n[server.http]", line: "line 2"},
+		{content: "a = \"open", line: "line 1"},
+	}
+	_, f := New("app.ini")
+	for _, tc := range tests {
+		err, _ := f.Parse([]byte(tc.content))
+		if err == nil || !strings.Contains(err.Error(), tc.line) {
+			t.Errorf("%q: got=%v, want error at %s", tc.content, err, tc.line)
+		}
+	}
+}
+
+func TestPropertiesParse(t *testing.T) {
+	content := `# comment
+! comment
+server.http.host = 0.0.0.0
+server.http.port:8080
+server.http.motd first \
+    second
+key\ with\ spaces = \u00e9t\u00e9
+path=C:\\conf\\app
+empty=
+`
+	_, f := New("app.properties")
+	err, m := f.Parse([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"server": map[string]interface{}{
+			"http": map[string]interface{}{"host": "0.0.0.0", "port": "8080", "motd": "first second"},
+		},
+		"key with spaces": "été",
+		"path":            `C:\conf\app`,
+		"empty":           "",
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got=%v, want=%v", m, want)
+	}
+}
+
+func TestPropertiesValueAndPrefix(t *testing.T) {
+	content := `log4j.rootLogger=DEBUG, A1
+log4j.appender.A1=org.apache.log4j.ConsoleAppender
+log4j.appender.A1.layout=org.apache.log4j.PatternLayout
+log4j.appender.A1.layout.ConversionPattern=%-4r [%t] %-5p %c - %m%n
+log4j.logger.com.foo.Bar=WARN
+log4j.logger.com.foo=INFO
+`
+	_, f := New("app.properties")
+	err, m := f.Parse([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"log4j": map[string]interface{}{
+			"rootLogger": "DEBUG, A1",
+			"appender": map[string]interface{}{
+				"A1": map[string]interface{}{
+					"#value": "org.apache.log4j.ConsoleAppender",
+					"layout": map[string]interface{}{
+						"#value":            "org.apache.log4j.PatternLayout",
+						"ConversionPattern": "%-4r [%t] %-5p %c - %m%n",
+					},
+				},
+			},
+			"logger": map[string]interface{}{
+				"com": map[string]interface{}{
+					"foo": map[string]interface{}{"#value": "INFO", "Bar": "WARN"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got=%v, want=%v", m, want)
+	}
+
+	err, out := f.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err, m = f.Parse(out); err != nil || !reflect.DeepEqual(m, want) {
+		t.Errorf("round trip: err=%v, got=%v, want=%v\n%s", err, m, want, out)
+	}
+}
+
+func TestPropertiesParseError(t *testing.T) {
+	_, f := New("app.properties")
+	for _, content := range []string{"a=1\nb=\\u12", "=value"} {
+		if err, _ := f.Parse([]byte(content)); err == nil || !strings.Contains(err.Error(), "line") {
+			t.Errorf("%q: got=%v, want error with line", content, err)
+		}
+	}
+}
+
+func TestLevelSplitRoundTrip(t *testing.T) {
+	config := map[string]interface{}{
+		"name":  "app",
+		"blank": "",
+		"server": map[string]interface{}{
+			"http": map[string]interface{}{
+				"host":   " padded ",
+				"motd":   "first line\nsecond line",
+				"quoted": `"x" = y: #z \ w`,
+			},
+		},
+	}
+	for _, file := range []string{"app.ini", "app.properties"} {
+		_, f := New(file)
+		err, content := f.Marshal(config)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		err, m := f.Parse(content)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", file, err, content)
+		}
+		if !reflect.DeepEqual(m, config) {
+			t.Errorf("%s: got=%v, want=%v\n%s", file, m, config, content)
+		}
+	}
+}
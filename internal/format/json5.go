@@ -0,0 +1,344 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// JSONC and JSON5 unmarshaller
+
+package format
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// unmarshalJson5 parses JSON with comments and the JSON5 extensions into the config map pointed to by v:
+// // and /* */ comments, trailing commas, unquoted keys, single quoted strings, hex numbers,
+// leading or trailing decimal points, Infinity and NaN. Values have the types json.Unmarshal produces,
+// errors point at the line and column of the content
+func unmarshalJson5(content []byte, v interface{}) error {
+	config, err := configMap(v)
+	if err != nil {
+		return err
+	}
+	p := &json5Parser{src: string(content)}
+	p.skip()
+	if p.pos < len(p.src) && p.src[p.pos] != '{' {
+		return p.errorf("expected an object")
+	}
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	if p.skip(); p.pos < len(p.src) {
+		return p.errorf("unexpected %s after the object", p.char())
+	}
+	if value != nil {
+		for key, elem := range value.(map[string]interface{}) {
+			config[key] = elem
+		}
+	}
+	return p.err
+}
+
+type json5Parser struct {
+	src string
+	pos int
+	err error // unterminated comment found by skip
+}
+
+// errorf returns an error at the current position
+func (p *json5Parser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.src[:p.pos], "\n") + 1
+	column := utf8.RuneCountInString(p.src[strings.LastIndexByte(p.src[:p.pos], '\n')+1:p.pos]) + 1
+	return fmt.Errorf("line %d, column %d: %s", line, column, fmt.Sprintf(format, args...))
+}
+
+// char describes the char at the current position
+func (p *json5Parser) char() string {
+	if p.pos >= len(p.src) {
+		return "end of content"
+	}
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	return strconv.QuoteRune(r)
+}
+
+// skip skips whitespace and comments
+func (p *json5Parser) skip() {
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		switch {
+		case unicode.IsSpace(r) || r == '\ufeff':
+			p.pos += size
+		case strings.HasPrefix(p.src[p.pos:], "//"):
+			end := strings.IndexByte(p.src[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.src)
+				return
+			}
+			p.pos += end
+		case strings.HasPrefix(p.src[p.pos:], "/*"):
+			end := strings.Index(p.src[p.pos+2:], "*/")
+			if end < 0 {
+				if p.err == nil {
+					p.err = p.errorf("unterminated comment")
+				}
+				p.pos = len(p.src)
+				return
+			}
+			p.pos += end + 4
+		default:
+			return
+		}
+	}
+}
+
+func (p *json5Parser) value() (interface{}, error) {
+	p.skip()
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of content, expected a value")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"' || c == '\'':
+		return p.string()
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		return p.number()
+	}
+	start := p.pos
+	switch word := p.identifier(); word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "Infinity":
+		return math.Inf(1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	p.pos = start
+	return nil, p.errorf("unexpected %s, expected a value", p.char())
+}
+
+func (p *json5Parser) object() (interface{}, error) {
+	p.pos++ // {
+	m := make(map[string]interface{})
+	for {
+		p.skip()
+		if p.pos < len(p.src) && p.src[p.pos] == '}' {
+			p.pos++
+			return m, nil
+		}
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		if p.skip(); p.pos >= len(p.src) || p.src[p.pos] != ':' {
+			return nil, p.errorf("unexpected %s, expected : after key %q", p.char(), key)
+		}
+		p.pos++
+		if m[key], err = p.value(); err != nil {
+			return nil, err
+		}
+		if err := p.next('}'); err != nil {
+			return nil, err
+		}
+		if p.src[p.pos-1] == '}' {
+			return m, nil
+		}
+	}
+}
+
+func (p *json5Parser) array() (interface{}, error) {
+	p.pos++ // [
+	s := make([]interface{}, 0)
+	for {
+		p.skip()
+		if p.pos < len(p.src) && p.src[p.pos] == ']' {
+			p.pos++
+			return s, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, value)
+		if err := p.next(']'); err != nil {
+			return nil, err
+		}
+		if p.src[p.pos-1] == ']' {
+			return s, nil
+		}
+	}
+}
+
+// next consumes the comma following a member, or the closing char
+func (p *json5Parser) next(closing byte) error {
+	if p.skip(); p.err != nil {
+		return p.err
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == ',' || p.src[p.pos] == closing) {
+		p.pos++
+		return nil
+	}
+	return p.errorf("unexpected %s, expected , or %c", p.char(), closing)
+}
+
+func (p *json5Parser) key() (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == '"' || p.src[p.pos] == '\'') {
+		return p.string()
+	}
+	if key := p.identifier(); key != "" {
+		return key, nil
+	}
+	return "", p.errorf("unexpected %s, expected a key", p.char())
+}
+
+// identifier consumes an unquoted key or literal
+func (p *json5Parser) identifier() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if r != '_' && r != '$' && !unicode.IsLetter(r) && (p.pos == start || !unicode.IsDigit(r)) {
+			break
+		}
+		p.pos += size
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *json5Parser) string() (string, error) {
+	quote := p.src[p.pos]
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\n':
+			return "", p.errorf("unterminated string")
+		case c == '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+			continue
+		default:
+			b.WriteByte(c)
+		}
+		p.pos++
+	}
+	p.pos = start
+	return "", p.errorf("unterminated string")
+}
+
+// escape consumes the escape sequence at the current position
+func (p *json5Parser) escape(b *strings.Builder) error {
+	p.pos++ // backslash
+	if p.pos >= len(p.src) {
+		return p.errorf("unterminated string")
+	}
+	c := p.src[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case 't':
+		b.WriteByte('\t')
+	case 'v':
+		b.WriteByte('\v')
+	case '0':
+		b.WriteByte(0)
+	case '\n':
+		// line continuation
+	case 'x', 'u':
+		n := 2
+		if c == 'u' {
+			n = 4
+		}
+		if p.pos+n > len(p.src) {
+			return p.errorf("malformed \\%c escape", c)
+		}
+		r, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+		if err != nil {
+			return p.errorf("malformed \\%c escape", c)
+		}
+		p.pos += n
+		if utf16Surrogate(rune(r)) && strings.HasPrefix(p.src[p.pos:], `\u`) && p.pos+6 <= len(p.src) {
+			if low, err := strconv.ParseUint(p.src[p.pos+2:p.pos+6], 16, 32); err == nil {
+				r = uint64(0x10000 + (r-0xd800)<<10 + (low - 0xdc00))
+				p.pos += 6
+			}
+		}
+		b.WriteRune(rune(r))
+	default:
+		b.WriteByte(c)
+	}
+	return nil
+}
+
+func utf16Surrogate(r rune) bool {
+	return r >= 0xd800 && r < 0xdc00
+}
+
+func (p *json5Parser) number() (interface{}, error) {
+	start := p.pos
+	sign := 1.0
+	if c := p.src[p.pos]; c == '+' || c == '-' {
+		if c == '-' {
+			sign = -1
+		}
+		p.pos++
+	}
+	if strings.HasPrefix(p.src[p.pos:], "Infinity") {
+		p.pos += len("Infinity")
+		return sign * math.Inf(1), nil
+	}
+	if strings.HasPrefix(p.src[p.pos:], "NaN") {
+		p.pos += len("NaN")
+		return math.NaN(), nil
+	}
+	digits := p.pos
+	for p.pos < len(p.src) && strings.IndexByte("0123456789abcdefABCDEFxX.+-", p.src[p.pos]) >= 0 {
+		// a sign only follows an exponent
+		if c := p.src[p.pos]; (c == '+' || c == '-') && !strings.ContainsAny(p.src[p.pos-1:p.pos], "eE") {
+			break
+		}
+		p.pos++
+	}
+	text := p.src[digits:p.pos]
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		n, err := strconv.ParseUint(text[2:], 16, 64)
+		if err != nil {
+			p.pos = start
+			return nil, p.errorf("invalid number %s", p.src[start:digits]+text)
+		}
+		return sign * float64(n), nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil || strings.ContainsAny(text, "xX") {
+		p.pos = start
+		return nil, p.errorf("invalid number %s", p.src[start:digits]+text)
+	}
+	return sign * f, nil
+}
@@ -0,0 +1,83 @@
+package format
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJson5Parse(t *testing.T) {
+	content := `// operators may comment
+{
+  /* the http server */
+  server: {
+    http: {
+      host: '0.0.0.0',
+      "port": 8080, // trailing comma below
+    },
+  },
+  tags: ["a", 'b\'s',],
+  hex: 0xFF,
+  ratio: .5,
+  total: +1e3,
+  escaped: "tab\tline\
+ continued é",
+  empty: null,
+}
+`
+	for _, file := range []string{"app.jsonc", "app.json5"} {
+		_, f := New(file)
+		err, m := f.Parse([]byte(content))
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		want := map[string]interface{}{
+			"server": map[string]interface{}{
+				"http": map[string]interface{}{"host": "0.0.0.0", "port": float64(8080)},
+			},
+			"tags":    []interface{}{"a", "b's"},
+			"hex":     float64(255),
+			"ratio":   0.5,
+			"total":   float64(1000),
+			"escaped": "tab\tline continued é",
+			"empty":   nil,
+		}
+		if !reflect.DeepEqual(m, want) {
+			t.Errorf("%s: got=%v, want=%v", file, m, want)
+		}
+	}
+}
+
+func TestJson5Special(t *testing.T) {
+	_, f := New("app.json5")
+	err, m := f.Parse([]byte("{inf: -Infinity, nan: NaN}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(m["inf"].(float64), -1) || !math.IsNaN(m["nan"].(float64)) {
+		t.Errorf("got=%v", m)
+	}
+}
+
+func TestJson5ParseError(t *testing.T) {
+	tests := []struct {
+		content string
+		at      string
+	}{
+		{content: "{\n  port: 8080\n  host: 'x'\n}", at: "line 3, column 3"},
+		{content: "{\n  /* open\n}", at: "line 2, column 3"},
+		{content: "{\n  host: 'x\n}", at: "line 2, column 11"},
+		{content: "{\n  port: 80a\n}", at: "line 2, column 9"},
+		{content: "{\n  port 8080\n}", at: "line 2, column 8"},
+		{content: "{\"a\": 1} []", at: "line 1, column 10"},
+		{content: "[1]", at: "line 1, column 1"},
+	}
+	_, f := New("app.jsonc")
+	for _, tc := range tests {
+		err, _ := f.Parse([]byte(tc.content))
+		if err == nil || !strings.Contains(err.Error(), tc.at) {
+			t.Errorf("%q: got=%v, want error at %s", tc.content, err, tc.at)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// nesting of flat keys on a level split
+
+package format
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// levels returns the nested keys of key split on split
+func levels(key, split string) []string {
+	if split == "" {
+		return []string{key}
+	}
+	return strings.Split(key, split)
+}
+
+// nestMap returns the map at the nested keys of key in m, creating missing levels
+func nestMap(m map[string]interface{}, key, split string) (map[string]interface{}, error) {
+	return nestLevels(m, key, levels(key, split))
+}
+
+func nestLevels(m map[string]interface{}, key string, keys []string) (map[string]interface{}, error) {
+	for _, level := range keys {
+		switch next := m[level].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			m[level] = child
+			m = child
+		case map[string]interface{}:
+			m = next
+		default:
+			return nil, fmt.Errorf("key %s conflicts with the value of %s", key, level)
+		}
+	}
+	return m, nil
+}
+
+// nest sets value at the nested keys of key in m, a later value of the same key wins
+func nest(m map[string]interface{}, key, split string, value interface{}) error {
+	keys := levels(key, split)
+	m, err := nestLevels(m, key, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, ok := m[last].(map[string]interface{}); ok {
+		return fmt.Errorf("key %s conflicts with the nested keys of %s", key, last)
+	}
+	m[last] = value
+	return nil
+}
+
+// leaf is a value of a nested config map with the path of its keys
+type leaf struct {
+	key   string
+	value interface{}
+}
+
+// flat returns the values of m that are not maps keyed by their paths joined with split, sorted
+func flat(m map[string]interface{}, prefix, split string) []leaf {
+	var leaves []leaf
+	for key, value := range m {
+		if child, ok := value.(map[string]interface{}); ok {
+			leaves = append(leaves, flat(child, prefix+key+split, split)...)
+			continue
+		}
+		leaves = append(leaves, leaf{key: prefix + key, value: value})
+	}
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].key < leaves[j].key })
+	return leaves
+}
+
+// scalar formats a value of a format of string values
+func scalar(key string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []interface{}, map[string]interface{}:
+		return "", fmt.Errorf("can not marshal %T value of %s", value, key)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// configMap returns the config map pointed to by v, v is set to an empty map if it is nil
+func configMap(v interface{}) (map[string]interface{}, error) {
+	config, ok := v.(*map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("can not unmarshal into %T", v)
+	}
+	if *config == nil {
+		*config = make(map[string]interface{})
+	}
+	return *config, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,181 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// Java .properties unmarshaller and marshaller
+
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// propertiesValueKey keys the value of a key that also prefixes nested keys
+const propertiesValueKey = "#value"
+
+// propertiesFormat parses .properties content: `key=value`, `key: value` or `key value` lines
+// with # and ! comment lines. Keys are nested on opts.LevelSplit, values are strings.
+// The value of a key that also prefixes other keys, such as log4j.appender.A1 next to
+// log4j.appender.A1.layout, is keyed by #value under the key: log4j.appender.A1.#value.
+// A line ending with a backslash continues on the next line, keys and values read
+// the escapes \t \n \r \f \uXXXX, and a backslash before any other char keeps the char
+func propertiesFormat(opts Options) (Unmarshaller, Marshaller) {
+	return func(content []byte, v interface{}) error {
+			return unmarshalProperties(content, v, opts.LevelSplit)
+		}, func(v interface{}) ([]byte, error) {
+			return marshalProperties(v, opts.LevelSplit)
+		}
+}
+
+func unmarshalProperties(content []byte, v interface{}, split string) error {
+	config, err := configMap(v)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		for continued(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		key, value := splitProperty(line)
+		if key, err = unescapeProperty(key); err == nil {
+			value, err = unescapeProperty(value)
+		}
+		if err == nil && key == "" {
+			err = fmt.Errorf("empty key")
+		}
+		if err == nil {
+			err = nestProperty(config, key, split, value)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	return nil
+}
+
+// nestProperty sets value at the nested keys of key in m like nest,
+// moving the value of a key that prefixes other keys under propertiesValueKey
+func nestProperty(m map[string]interface{}, key, split string, value string) error {
+	keys := levels(key, split)
+	for _, level := range keys[:len(keys)-1] {
+		switch next := m[level].(type) {
+		case map[string]interface{}:
+			m = next
+		case nil:
+			child := make(map[string]interface{})
+			m[level] = child
+			m = child
+		default:
+			child := map[string]interface{}{propertiesValueKey: next}
+			m[level] = child
+			m = child
+		}
+	}
+	last := keys[len(keys)-1]
+	if child, ok := m[last].(map[string]interface{}); ok {
+		child[propertiesValueKey] = value
+		return nil
+	}
+	m[last] = value
+	return nil
+}
+
+// continued reports whether line ends with an odd number of backslashes
+func continued(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, `\`))
+	return n%2 == 1
+}
+
+// splitProperty splits line at the first unescaped =, : or whitespace, keeping escapes
+func splitProperty(line string) (string, string) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			end = i
+			break
+		}
+	}
+	key, rest := line[:end], strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	if continued(rest) {
+		// a trailing backslash on the last line escapes nothing
+		rest = rest[:len(rest)-1]
+	}
+	return key, rest
+}
+
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("malformed \\u escape in %s", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\u escape in %s", s)
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+var propertiesEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "\f", `\f`)
+var propertiesKeyEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "\f", `\f`,
+	" ", `\ `, "=", `\=`, ":", `\:`, "#", `\#`, "!", `\!`)
+
+func marshalProperties(v interface{}, split string) ([]byte, error) {
+	config, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("properties can not marshal %T", v)
+	}
+	var buf bytes.Buffer
+	for _, l := range flat(config, "", split) {
+		s, err := scalar(l.key, l.value)
+		if err != nil {
+			return nil, err
+		}
+		s = propertiesEscaper.Replace(s)
+		if strings.HasPrefix(s, " ") {
+			s = `\` + s
+		}
+		key := strings.TrimSuffix(l.key, split+propertiesValueKey)
+		fmt.Fprintf(&buf, "%s=%s\n", propertiesKeyEscaper.Replace(key), s)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// registry of formats by name and file ext
+
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/BurntSushi/toml"
+	"github.com/enpsl/conf-reload/internal/app"
+	"github.com/enpsl/conf-reload/internal/decode"
+	"github.com/enpsl/conf-reload/internal/errors"
+	yaml "gopkg.in/yaml.v3"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Unmarshaller parses content into the config map pointed to by v
+type Unmarshaller func(content []byte, v interface{}) error
+
+// Marshaller encodes a config map into content
+type Marshaller func(v interface{}) ([]byte, error)
+
+// Options configures the formats depending on the engine, such as how flat keys are nested
+type Options struct {
+	LevelSplit    string // nests the sections and keys of ini and properties
+	EnvSeparator  string // nests the keys of dotenv, such as "__" for SERVER__HTTP__PORT, empty keeps them flat
+	XMLAttrPrefix string // prefixes the keys of xml attributes
+}
+
+// configurable returns the Unmarshaller and Marshaller of a format configured by opts
+type configurable func(opts Options) (Unmarshaller, Marshaller)
+
+// decoding returns the decoder of a format configured by opts
+type decoding func(opts Options) *decode.Decoder
+
+var registry = struct {
+	mu      sync.RWMutex
+	formats map[FileExtType]*Format
+	exts    map[string]FileExtType
+}{
+	formats: make(map[FileExtType]*Format),
+	exts:    make(map[string]FileExtType),
+}
+
+func init() {
+	_ = Register(string(FileExtToml), []string{".toml"}, toml.Unmarshal, marshalToml)
+	_ = Register(string(FileExtJson), []string{".json"}, json.Unmarshal, marshalJson)
+	_ = Register(string(FileExtYaml), []string{".yaml", ".yml"}, yaml.Unmarshal, yaml.Marshal)
+	_ = Register(string(FileExtHcl), []string{".hcl"}, unmarshalHcl, nil)
+	registerTagged(FileExtJsonc, []string{".jsonc"}, unmarshalJson5, marshalJson, string(FileExtJson))
+	registerTagged(FileExtJson5, []string{".json5"}, unmarshalJson5, marshalJson, string(FileExtJson))
+	registerConfigurable(FileExtIni, []string{".ini"}, iniFormat)
+	registerConfigurable(FileExtProperties, []string{".properties"}, propertiesFormat)
+	registerConfigurable(FileExtDotenv, []string{".env"}, dotenvFormat)
+	registerConfigurable(FileExtXml, []string{".xml"}, xmlFormat)
+	registerDecoding(FileExtXml, xmlDecoding)
+}
+
+// registerTagged registers a format decoding struct tags named tag instead of its name,
+// such as the json tags of the json dialects
+func registerTagged(ext FileExtType, exts []string, unmarshal Unmarshaller, marshal Marshaller, tag string) {
+	_ = Register(string(ext), exts, unmarshal, marshal)
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.formats[ext].tag = tag
+}
+
+// registerConfigurable registers a format with the default Options,
+// Format.WithOptions nests them otherwise
+func registerConfigurable(ext FileExtType, exts []string, c configurable) {
+	unmarshal, marshal := c(Options{LevelSplit: app.DefaultLevelSplit, XMLAttrPrefix: app.DefaultXMLAttrPrefix})
+	_ = Register(string(ext), exts, unmarshal, marshal)
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.formats[ext].configurable = c
+}
+
+// registerDecoding sets the decoder of a configurable format registered as ext
+func registerDecoding(ext FileExtType, d decoding) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	f := registry.formats[ext]
+	f.decoding = d
+	f.decoder = d(Options{LevelSplit: app.DefaultLevelSplit, XMLAttrPrefix: app.DefaultXMLAttrPrefix})
+}
+
+// Register adds the format name parsing files of exts, replacing a format of the same name.
+// An ext registered by another format is taken over by name, marshal may be nil
+func Register(name string, exts []string, unmarshal Unmarshaller, marshal Marshaller) error {
+	if name == "" || unmarshal == nil {
+		return errors.ErrFormat(errors.ErrInvalidFormat, errors.New("format needs a name and an unmarshaller"))
+	}
+	normalized := make([]string, 0, len(exts))
+	for _, ext := range exts {
+		if ext = strings.TrimPrefix(ext, "."); ext == "" {
+			return errors.ErrFormat(errors.ErrInvalidFormat, errors.New("format "+name+" has an empty ext"))
+		}
+		normalized = append(normalized, "."+ext)
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	extType := FileExtType(name)
+	for ext, owner := range registry.exts {
+		if owner == extType {
+			delete(registry.exts, ext)
+		}
+	}
+	for _, ext := range normalized {
+		registry.exts[ext] = extType
+	}
+	registry.formats[extType] = &Format{ext: extType, tag: name, unmarshaller: unmarshal, marshaller: marshal}
+	return nil
+}
+
+// Snapshot saves the registered formats and returns a func restoring them,
+// so that a test registering formats can undo it with t.Cleanup
+func Snapshot() func() {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	formats := make(map[FileExtType]*Format, len(registry.formats))
+	for name, f := range registry.formats {
+		formats[name] = f
+	}
+	exts := make(map[string]FileExtType, len(registry.exts))
+	for ext, name := range registry.exts {
+		exts[ext] = name
+	}
+	return func() {
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+		registry.formats, registry.exts = formats, exts
+	}
+}
+
+// Exts returns the registered file exts, sorted
+func Exts() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	exts := make([]string, 0, len(registry.exts))
+	for ext := range registry.exts {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+func lookup(ext FileExtType) (*Format, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	f, ok := registry.formats[ext]
+	return f, ok
+}
+
+func marshalJson(v interface{}) ([]byte, error) {
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func marshalToml(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// content sniffing of the Auto format
+
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/enpsl/conf-reload/internal/errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	// tomlTableHeader matches a [table] or [[array]] header line
+	tomlTableHeader = regexp.MustCompile(`(?m)^\s*\[\[?[\w."' -]+\]\]?\s*(#.*)?$`)
+	// yamlMappingKey matches a "key:" line
+	yamlMappingKey = regexp.MustCompile(`(?m)^\s*[\w."'-]+\s*:(\s|$)`)
+)
+
+// sniffed lists the formats the Auto format tells apart in order of precedence,
+// each with the signal of its content
+var sniffed = []struct {
+	ext    FileExtType
+	signal func(content []byte) bool
+}{
+	{ext: FileExtJson, signal: func(content []byte) bool { return bytes.HasPrefix(content, []byte("{")) }},
+	{ext: FileExtToml, signal: tomlTableHeader.Match},
+	{ext: FileExtYaml, signal: yamlMappingKey.Match},
+}
+
+// sniff detects the format of content among json, toml and yaml.
+// Of the formats parsing content, the first one whose signal is found wins:
+// a JSON object, a TOML table header, then a YAML mapping key.
+// Content that more than one format parses with no signal is reported ambiguous
+func sniff(content []byte) (error, *Format) {
+	content = bytes.TrimSpace(content)
+	var parsed []*Format
+	var failed []string
+	for _, s := range sniffed {
+		d, ok := lookup(s.ext)
+		if !ok {
+			continue
+		}
+		var config map[string]interface{}
+		if err := d.unmarshaller(content, &config); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", d.Name(), err))
+			continue
+		}
+		if s.signal(content) {
+			return nil, d
+		}
+		parsed = append(parsed, d)
+	}
+
+	switch len(parsed) {
+	case 1:
+		return nil, parsed[0]
+	case 0:
+		return fmt.Errorf("%w: no format parses the content [%s]", errors.ErrUnmarshaller, strings.Join(failed, "; ")), nil
+	}
+	names := make([]string, 0, len(parsed))
+	for _, d := range parsed {
+		names = append(names, d.Name())
+	}
+	return fmt.Errorf("%w: content parses as %s, force one of them", errors.ErrAmbiguousFormat, strings.Join(names, " and ")), nil
+}
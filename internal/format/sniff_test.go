@@ -0,0 +1,62 @@
+package format
+
+import (
+	"github.com/enpsl/conf-reload/internal/errors"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		desc    string
+		content string
+		want    FileExtType
+	}{
+		{desc: "json object", content: "{\n  \"server\": {\"port\": 8080}\n}", want: FileExtJson},
+		{desc: "toml table header", content: "[server]\nport = 8080", want: FileExtToml},
+		{desc: "toml without table", content: "port = 8080", want: FileExtToml},
+		{desc: "yaml mapping", content: "server:\n  port: 8080", want: FileExtYaml},
+		{desc: "yaml flow mapping", content: "server: {port: 8080}", want: FileExtYaml},
+	}
+	_, f := Named(Auto)
+	for _, tc := range tests {
+		err, d := f.Detect([]byte(tc.content))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.desc, err)
+		}
+		if d.Name() != string(tc.want) {
+			t.Errorf("%s: got=%s, want=%s", tc.desc, d.Name(), tc.want)
+		}
+		err, m := f.Parse([]byte(tc.content))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.desc, err)
+		}
+		if _, ok := m["server"]; !ok && tc.want != FileExtToml {
+			t.Errorf("%s: server missing in %v", tc.desc, m)
+		}
+	}
+}
+
+func TestSniffAmbiguous(t *testing.T) {
+	_, f := Named(Auto)
+	// content parsed before does not decide for ambiguous content
+	if err, _ := f.Parse([]byte("port: 8080")); err != nil {
+		t.Fatal(err)
+	}
+	err, _ := f.Parse([]byte("# nothing but a comment"))
+	if !errors.Is(err, errors.ErrAmbiguousFormat) {
+		t.Fatalf("got=%v, want=%v", err, errors.ErrAmbiguousFormat)
+	}
+	if f.Name() != Auto {
+		t.Errorf("got=%s, want=%s", f.Name(), Auto)
+	}
+}
+
+func TestSniffUnknown(t *testing.T) {
+	_, f := Named(Auto)
+	if err, _ := f.Parse([]byte("[unclosed")); !errors.Is(err, errors.ErrUnmarshaller) {
+		t.Errorf("got=%v, want=%v", err, errors.ErrUnmarshaller)
+	}
+	if err, _ := Named("conf"); !errors.Is(err, errors.ErrInvalidFormat) {
+		t.Errorf("got=%v, want=%v", err, errors.ErrInvalidFormat)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// XML unmarshaller
+
+package format
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/enpsl/conf-reload/internal/decode"
+	"io"
+	"strings"
+)
+
+// xmlTextKey is the key of the text of an element with attributes or child elements
+const xmlTextKey = "#text"
+
+// xmlFormat parses XML content into nested maps keyed by element names, starting with the root element.
+// An element with neither attributes nor child elements is its text, repeated elements are a slice,
+// attributes are keyed by their name after opts.XMLAttrPrefix and the text of an element
+// with attributes or child elements is keyed by #text. Values are strings.
+// A child element keyed as an attribute of its parent, possible with an empty prefix, is an error
+func xmlFormat(opts Options) (Unmarshaller, Marshaller) {
+	return func(content []byte, v interface{}) error {
+		return unmarshalXml(content, v, opts.XMLAttrPrefix)
+	}, nil
+}
+
+// xmlDecoding decodes xml configs through the xml struct tags as encoding/xml reads them
+func xmlDecoding(opts Options) *decode.Decoder {
+	return decode.NewXml(opts.XMLAttrPrefix, xmlTextKey)
+}
+
+type xmlElement struct {
+	name     string
+	children map[string]interface{}
+	attrs    map[string]bool
+	text     strings.Builder
+}
+
+// value returns the text of e, or its children with its text
+func (e *xmlElement) value() interface{} {
+	text := strings.TrimSpace(e.text.String())
+	if len(e.children) == 0 {
+		return text
+	}
+	if text != "" {
+		e.children[xmlTextKey] = text
+	}
+	return e.children
+}
+
+func unmarshalXml(content []byte, v interface{}, prefix string) error {
+	config, err := configMap(v)
+	if err != nil {
+		return err
+	}
+	stack := []*xmlElement{{children: config}}
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			e := &xmlElement{name: t.Name.Local, children: make(map[string]interface{}), attrs: make(map[string]bool)}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				e.attrs[prefix+attr.Name.Local] = true
+				addXml(e.children, prefix+attr.Name.Local, attr.Value)
+			}
+			stack = append(stack, e)
+		case xml.CharData:
+			stack[len(stack)-1].text.Write(t)
+		case xml.EndElement:
+			e := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			parent := stack[len(stack)-1]
+			if parent.attrs[e.name] {
+				line, _ := decoder.InputPos()
+				return fmt.Errorf("line %d: element %s collides with attribute %s of %s, set an attribute prefix",
+					line, e.name, e.name, parent.name)
+			}
+			addXml(parent.children, e.name, e.value())
+		}
+	}
+}
+
+// addXml sets value at key in m, a repeated key makes a slice of its values
+func addXml(m map[string]interface{}, key string, value interface{}) {
+	switch prev := m[key].(type) {
+	case nil:
+		m[key] = value
+	case []interface{}:
+		m[key] = append(prev, value)
+	default:
+		m[key] = []interface{}{prev, value}
+	}
+}
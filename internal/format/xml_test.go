@@ -0,0 +1,95 @@
+package format
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestXmlParse(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<config xmlns="urn:app">
+  <!-- the http server -->
+  <server name="http">
+    <host>0.0.0.0</host>
+    <port>8080</port>
+    <motd lang="en">hello</motd>
+  </server>
+  <depend>tcp</depend>
+  <depend>ip</depend>
+  <empty/>
+</config>`
+	tests := []struct {
+		prefix string
+		want   map[string]interface{}
+	}{
+		{
+			prefix: "@",
+			want: map[string]interface{}{
+				"config": map[string]interface{}{
+					"server": map[string]interface{}{
+						"@name": "http",
+						"host":  "0.0.0.0",
+						"port":  "8080",
+						"motd":  map[string]interface{}{"@lang": "en", "#text": "hello"},
+					},
+					"depend": []interface{}{"tcp", "ip"},
+					"empty":  "",
+				},
+			},
+		},
+		{
+			prefix: "-",
+			want: map[string]interface{}{
+				"config": map[string]interface{}{
+					"server": map[string]interface{}{
+						"-name": "http",
+						"host":  "0.0.0.0",
+						"port":  "8080",
+						"motd":  map[string]interface{}{"-lang": "en", "#text": "hello"},
+					},
+					"depend": []interface{}{"tcp", "ip"},
+					"empty":  "",
+				},
+			},
+		},
+	}
+	_, f := New("app.xml")
+	for _, tc := range tests {
+		err, m := f.WithOptions(Options{XMLAttrPrefix: tc.prefix}).Parse([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, tc.want) {
+			t.Errorf("prefix %q: got=%v, want=%v", tc.prefix, m, tc.want)
+		}
+	}
+}
+
+func TestXmlParseError(t *testing.T) {
+	_, f := New("app.xml")
+	err, _ := f.Parse([]byte("<config>\n  <port>8080</host>\n</config>"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("got=%v, want error at line 2", err)
+	}
+}
+
+func TestXmlParseAttrCollision(t *testing.T) {
+	_, f := New("app.xml")
+	f = f.WithOptions(Options{})
+	err, m := f.Parse([]byte("<config>\n  <server port=\"80\"><host>0.0.0.0</host></server>\n</config>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"config": map[string]interface{}{"server": map[string]interface{}{"port": "80", "host": "0.0.0.0"}},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got=%v, want=%v", m, want)
+	}
+
+	err, _ = f.Parse([]byte("<config>\n  <server port=\"80\">\n    <port>8080</port>\n  </server>\n</config>"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") || !strings.Contains(err.Error(), "port") {
+		t.Errorf("got=%v, want collision error at line 3", err)
+	}
+}
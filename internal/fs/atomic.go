@@ -0,0 +1,34 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// atomic file write
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes content to a temp file next to path and renames it over path,
+// so path always holds either the old or the new content
+func WriteFileAtomic(path string, content []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if _, err = f.Write(content); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, perm)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
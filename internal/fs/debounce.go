@@ -0,0 +1,70 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// debounce coalesces bursts of file change events into a single notification
+
+package fs
+
+import (
+	"github.com/enpsl/conf-reload/internal/base"
+	"sync"
+	"time"
+)
+
+type debouncer struct {
+	window  time.Duration // quiet period after the last event
+	maxWait time.Duration // cap from the first event of a burst, 0 means no cap
+	fire    func(base.Event)
+	mu      sync.Mutex
+	timer   *time.Timer
+	first   time.Time  // first event of the pending burst
+	latest  base.Event // last event of the pending burst
+	gen     uint64     // bumped on every trigger, stale timers are ignored
+}
+
+func newDebouncer(window, maxWait time.Duration, fire func(base.Event)) *debouncer {
+	return &debouncer{window: window, maxWait: maxWait, fire: fire}
+}
+
+// trigger (re)starts the quiet period, fire is called with the latest event
+// once no event arrived for window, or once maxWait passed since the first event of the burst
+func (d *debouncer) trigger(event base.Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.latest = event
+	now := time.Now()
+	if d.timer == nil {
+		d.first = now
+	} else {
+		d.timer.Stop()
+	}
+	wait := d.window
+	if d.maxWait > 0 {
+		if deadline := d.first.Add(d.maxWait); now.Add(wait).After(deadline) {
+			wait = deadline.Sub(now)
+		}
+	}
+	d.gen++
+	gen := d.gen
+	d.timer = time.AfterFunc(wait, func() {
+		d.mu.Lock()
+		if gen != d.gen {
+			d.mu.Unlock()
+			return
+		}
+		d.timer = nil
+		event := d.latest
+		d.mu.Unlock()
+		d.fire(event)
+	})
+}
+
+// stop drops the pending burst
+func (d *debouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.gen++
+}
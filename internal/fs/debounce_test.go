@@ -0,0 +1,39 @@
+package fs
+
+import (
+	"github.com/enpsl/conf-reload/internal/base"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDebouncerCoalesce(t *testing.T) {
+	var fired int32
+	d := newDebouncer(50*time.Millisecond, 0, func(base.Event) {
+		atomic.AddInt32(&fired, 1)
+	})
+	for i := 0; i < 10; i++ {
+		d.trigger(base.NewEvent("test.toml", base.ReasonWrite))
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if got := atomic.LoadInt32(&fired); got != 1 {
+		t.Errorf("got=%d, want=%d", got, 1)
+	}
+}
+
+func TestDebouncerMaxWait(t *testing.T) {
+	var fired int32
+	d := newDebouncer(50*time.Millisecond, 100*time.Millisecond, func(base.Event) {
+		atomic.AddInt32(&fired, 1)
+	})
+	defer d.stop()
+	deadline := time.Now().Add(300 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		d.trigger(base.NewEvent("test.toml", base.ReasonWrite))
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := atomic.LoadInt32(&fired); got < 2 {
+		t.Errorf("constantly triggered debouncer fired %d times, want at least %d", got, 2)
+	}
+}
@@ -1,69 +1,93 @@
 // Copyright 2023 enpsl. All rights reserved.
 
 // Package fs is Instantiation of broker interface
-// File change notification based on fsnotify
-// conf-reload and its internal packages.
+// FsSource watches a config file, File change notification based on fsnotify
+// FsBroker composes it with the Format of the file ext
 
 package fs
 
 import (
-	"encoding/json"
-	"fmt"
-	"github.com/BurntSushi/toml"
+	"github.com/enpsl/conf-reload/internal/app"
 	"github.com/enpsl/conf-reload/internal/base"
 	"github.com/enpsl/conf-reload/internal/errors"
+	"github.com/enpsl/conf-reload/internal/format"
 	"github.com/enpsl/conf-reload/internal/log"
 	"github.com/fsnotify/fsnotify"
-	"github.com/mitchellh/mapstructure"
-	yaml "gopkg.in/yaml.v3"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
-type unmarshaller func([]byte, interface{}) error
-
+// FsBroker is a FsSource with the Format of the file ext
 type FsBroker struct {
+	*FsSource
+	*format.Format
+}
+
+// FsSource loads and watches a config file
+type FsSource struct {
 	logger       *log.Logger
-	notifyCh     chan struct{}
+	notifyCh     chan base.Event
+	mu           sync.Mutex // guards sending on and closing notifyCh
 	once         sync.Once
-	unmarshaller unmarshaller
 	dir          string
 	abs          string
-	ext          FileExtType
-	wg           sync.WaitGroup
+	pollInterval time.Duration
+	debouncer    *debouncer
+	done         chan struct{}
+	healthMu     sync.Mutex
+	health       base.Health
 }
 
-type FileExtType string
+type Option func(*FsSource)
 
-const FileExtToml FileExtType = "toml"
-const FileExtJson FileExtType = "json"
-const FileExtYaml FileExtType = "yaml"
+// WithDebounce coalesces a burst of change events into a single notification,
+// sent once no event arrived for window, or at the latest maxWait after the first event.
+// maxWait 0 means no cap, window 0 disables debouncing
+func WithDebounce(window, maxWait time.Duration) Option {
+	return func(fs *FsSource) {
+		if window > 0 {
+			fs.debouncer = newDebouncer(window, maxWait, fs.notify)
+		}
+	}
+}
 
-var UnmarshallerMap = map[FileExtType]unmarshaller{
-	FileExtToml: toml.Unmarshal,
-	FileExtJson: json.Unmarshal,
-	FileExtYaml: yaml.Unmarshal,
+// WithPollInterval watch the config file by polling at interval instead of fsnotify
+func WithPollInterval(interval time.Duration) Option {
+	return func(fs *FsSource) {
+		fs.pollInterval = interval
+	}
+}
+
+func NewFs(path string, logger *log.Logger, opts ...Option) (error, *FsBroker) {
+	err, f := format.New(path)
+	if err != nil {
+		return err, nil
+	}
+	return NewFsFormat(path, f, logger, opts...)
 }
 
-func ExtParser(file string) FileExtType {
-	ext := filepath.Ext(file)
-	switch ext {
-	case ".toml":
-		return FileExtToml
-	case ".json":
-		return FileExtJson
-	case ".yaml":
-		return FileExtYaml
-	case ".yml":
-		return FileExtYaml
+// NewFsFormat returns the FsBroker of the config file path parsed by f whatever its ext
+func NewFsFormat(path string, f *format.Format, logger *log.Logger, opts ...Option) (error, *FsBroker) {
+	err, source := NewSource(path, logger, opts...)
+	if err != nil {
+		return err, nil
+	}
+	return nil, &FsBroker{
+		FsSource: source,
+		Format:   f,
 	}
-	return ""
 }
 
-func NewFs(path string, logger *log.Logger) (error, *FsBroker) {
-	fs := new(FsBroker)
-	fs.notifyCh = make(chan struct{})
+// NewSource returns the FsSource of the config file path
+func NewSource(path string, logger *log.Logger, opts ...Option) (error, *FsSource) {
+	fs := new(FsSource)
+	fs.notifyCh = make(chan base.Event, 1)
+	fs.done = make(chan struct{})
+	for _, opt := range opts {
+		opt(fs)
+	}
 
 	abs, err := filepath.Abs(path)
 
@@ -74,91 +98,182 @@ func NewFs(path string, logger *log.Logger) (error, *FsBroker) {
 	fs.abs = abs
 
 	dir, err := base.FindParentDir(abs)
+	if errors.Is(err, os.ErrNotExist) {
+		// the file may be created later, LoadContent reports it missing until then
+		dir, err = filepath.Dir(abs), nil
+	}
 	if err != nil {
 		return errors.ErrFormat(errors.ErrInvalidFilePath, err), nil
 	}
 
-	ext_type := ExtParser(abs)
-	if _, ok := UnmarshallerMap[ext_type]; !ok {
-		return errors.ErrFormat(errors.ErrInvalidFileExt, errors.New("ext is unsupport")), nil
-	}
-	fs.unmarshaller = UnmarshallerMap[ext_type]
 	fs.dir = dir
 	fs.logger = logger
-	fs.ext = ext_type
 	return nil, fs
 }
 
-func (fs *FsBroker) Parse(content []byte) (error, map[string]interface{}) {
-	var config = make(map[string]interface{})
-	err := fs.unmarshaller(content, &config)
-	if err != nil {
-		return errors.ErrFormat(errors.ErrUnmarshaller, err), nil
+func (fs *FsSource) LoadContent() ([]byte, error) {
+	return os.ReadFile(fs.abs)
+}
+
+// Watch blocks until the broker is closed, sending a notification for every change of the config file.
+// Polling is used when a poll interval is set, or when fsnotify is not usable for the config dir
+func (fs *FsSource) Watch() {
+	if fs.pollInterval > 0 {
+		fs.poll(fs.pollInterval)
+		return
+	}
+	if err := fs.watchNotify(); err != nil {
+		fs.logger.Warnf("fsnotify unavailable, fall back to polling every %s: %s", app.DefaultPollInterval, err.Error())
+		fs.poll(app.DefaultPollInterval)
 	}
-	return nil, config
 }
 
-func (fs *FsBroker) LoadContent() ([]byte, error) {
-	return os.ReadFile(fs.abs)
+// watchNotify watches the config dir with the shared fsnotify watcher,
+// an error is returned if the watcher can not be set up.
+// Once set up, a lost watch degrades the broker and the watcher is recreated with backoff,
+// a reload is triggered as soon as the watch recovers and the config file exists
+func (fs *FsSource) watchNotify() error {
+	ready := false
+	backoff := app.DefaultRetryMinBackoff
+	for {
+		err := fs.watch(func() {
+			if ready {
+				fs.logger.Infof("watch of %s recovered", fs.abs)
+				if _, err := os.Stat(fs.abs); err == nil {
+					fs.changed(base.NewEvent(fs.abs, base.ReasonRecover))
+				}
+			}
+			ready = true
+			backoff = app.DefaultRetryMinBackoff
+		})
+		if err == nil {
+			return nil
+		}
+		if !ready {
+			return err
+		}
+		fs.setHealth(true, err)
+		fs.logger.Warnf("watch of %s lost, retry in %s: %s", fs.abs, backoff, err.Error())
+		select {
+		case <-fs.done:
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > app.DefaultRetryMaxBackoff {
+			backoff = app.DefaultRetryMaxBackoff
+		}
+	}
 }
 
-func (fs *FsBroker) Watch() {
-	w, err := fsnotify.NewWatcher()
+// watch subscribes to the shared fsnotify watcher until the broker is closed or the watch is lost,
+// ready is called once the watch is set up
+func (fs *FsSource) watch(ready func()) error {
+	watchDir := fs.watchDir()
+	sub, err := mux.subscribe(watchDir)
 	if err != nil {
-		fs.logger.Fatalf("new file watcher error:%w", err.Error())
+		return err
 	}
-	defer w.Close()
+	defer mux.unsubscribe(sub)
+	fs.setHealth(true, nil)
+	ready()
 
-	configFile := filepath.Clean(fs.abs)
 	realConfigFile, _ := filepath.EvalSymlinks(fs.abs)
 
-	fs.wg.Add(1)
-	go func() {
-		defer fs.wg.Done()
-		for {
-			select {
-			case event := <-w.Events:
-				// Compatible with soft links
-				currentConfigFile, _ := filepath.EvalSymlinks(fs.abs)
-				const writeOrCreateMask = fsnotify.Write | fsnotify.Create
-				if (filepath.Clean(event.Name) == configFile && event.Op&writeOrCreateMask != 0) ||
-					(currentConfigFile != "" && currentConfigFile != realConfigFile) {
-					realConfigFile = currentConfigFile
-					fs.logger.Debugf("modified file:%s, %s", event.Name, realConfigFile)
-					fs.notifyCh <- struct{}{}
-				}
-			case err := <-w.Errors:
-				fs.logger.Errorf("read watch error:" + err.Error())
+	for {
+		select {
+		case <-fs.done:
+			return nil
+		case event := <-sub.events:
+			if current, reason, ok := fs.isChange(event, realConfigFile); ok {
+				realConfigFile = current
+				fs.logger.Debugf("modified file:%s, %s", event.Name, realConfigFile)
+				fs.changed(base.NewEvent(fs.abs, reason))
 			}
+		case err := <-sub.lost:
+			fs.logger.Errorf("read watch error:" + err.Error())
+			return err
 		}
-	}()
-	err = w.Add(fs.dir)
+	}
+}
+
+// setHealth records the watch state, err is the reason of a degraded watch
+func (fs *FsSource) setHealth(watching bool, err error) {
+	fs.healthMu.Lock()
+	defer fs.healthMu.Unlock()
+	health := base.Health{Watching: watching, Since: time.Now()}
 	if err != nil {
-		fs.logger.Fatal(err)
+		health.Degraded = true
+		health.Reason = err.Error()
 	}
-	fs.wg.Wait()
+	if health.Watching == fs.health.Watching && health.Reason == fs.health.Reason {
+		return
+	}
+	fs.health = health
+}
+
+// Health returns the watch state
+func (fs *FsSource) Health() base.Health {
+	fs.healthMu.Lock()
+	defer fs.healthMu.Unlock()
+	return fs.health
 }
 
-func (fs *FsBroker) Decode(input interface{}, output interface{}, weaklyTypedInput bool) error {
-	config := mapstructure.DecoderConfig{
-		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
-		Result:           output,
-		TagName:          string(fs.ext),
-		WeaklyTypedInput: weaklyTypedInput,
+// isChange reports whether event changed the config file and why,
+// and returns the file the config path currently resolves to.
+// A swap of a symlink on the path, such as ..data of a ConfigMap volume,
+// changes the resolved file and is reported once for all events of the swap
+func (fs *FsSource) isChange(event fsnotify.Event, realConfigFile string) (string, base.EventReason, bool) {
+	// Compatible with soft links
+	currentConfigFile, _ := filepath.EvalSymlinks(fs.abs)
+	const writeOrCreateMask = fsnotify.Write | fsnotify.Create
+	switch {
+	case currentConfigFile != "" && currentConfigFile != realConfigFile:
+		return currentConfigFile, base.ReasonSymlink, true
+	case filepath.Clean(event.Name) == fs.abs && event.Op&writeOrCreateMask != 0:
+		return currentConfigFile, base.ReasonWrite, true
 	}
-	decoder, err := mapstructure.NewDecoder(&config)
-	if err != nil {
-		return errors.ErrFormat(errors.ErrBrokerDecode, fmt.Errorf("decode err %w", err))
+	return realConfigFile, "", false
+}
+
+// changed reports a change of the config file, debounced if configured
+func (fs *FsSource) changed(event base.Event) {
+	if fs.debouncer != nil {
+		fs.debouncer.trigger(event)
+		return
 	}
-	return decoder.Decode(input)
+	fs.notify(event)
 }
 
-func (fs *FsBroker) Notify() <-chan struct{} {
+// notify sends event without blocking the watcher, latest wins:
+// a notification the engine did not receive yet is replaced by event
+func (fs *FsSource) notify(event base.Event) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	select {
+	case <-fs.done:
+		return
+	default:
+	}
+	select {
+	case <-fs.notifyCh:
+	default:
+	}
+	fs.notifyCh <- event
+}
+
+func (fs *FsSource) Notify() <-chan base.Event {
 	return fs.notifyCh
 }
 
-func (fs *FsBroker) Close() error {
+func (fs *FsSource) Close() error {
 	fs.once.Do(func() {
+		close(fs.done)
+		fs.setHealth(false, nil)
+		if fs.debouncer != nil {
+			fs.debouncer.stop()
+		}
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
 		close(fs.notifyCh)
 	})
 	return nil
@@ -1,9 +1,12 @@
 package fs
 
 import (
+	"github.com/enpsl/conf-reload/internal/base"
 	"github.com/enpsl/conf-reload/internal/log"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestFsBrokerParse(t *testing.T) {
@@ -48,3 +51,101 @@ func TestFsBrokerParse(t *testing.T) {
 			test.path, content, test.wantedValue)
 	}
 }
+
+func TestFsBrokerPoll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.toml")
+	if err := os.WriteFile(path, []byte("port=8080"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err, broker := NewFs(path, log.NewLogger(nil), WithPollInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer broker.Close()
+	go broker.Watch()
+
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("port=8081"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-broker.Notify():
+	case <-time.After(time.Second):
+		t.Fatal("broker.Watch did not notify the modified file")
+	}
+}
+
+func TestFsBrokerWatchRecover(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "conf")
+	path := filepath.Join(dir, "test.toml")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("port=8080"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err, broker := NewFs(path, log.NewLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer broker.Close()
+	go broker.Watch()
+
+	waitHealth := func(degraded bool) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if h := broker.Health(); h.Watching && h.Degraded == degraded {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("broker.Health() = %+v, want degraded=%t", broker.Health(), degraded)
+	}
+	waitHealth(false)
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	waitHealth(true)
+
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("port=8081"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-broker.Notify():
+	case <-time.After(2 * time.Second):
+		t.Fatal("broker.Watch did not notify the recreated file")
+	}
+	waitHealth(false)
+}
+
+func TestFsBrokerNotifyLatestWins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.toml")
+	if err := os.WriteFile(path, []byte("port=8080"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err, broker := NewFs(path, log.NewLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer broker.Close()
+
+	reasons := []base.EventReason{base.ReasonWrite, base.ReasonSymlink, base.ReasonPoll}
+	for _, reason := range reasons {
+		// must not block while nobody receives
+		broker.notify(base.NewEvent(path, reason))
+	}
+	event := <-broker.Notify()
+	if event.Reason != base.ReasonPoll || event.Name != path {
+		t.Errorf("got=%+v, want reason=%s name=%s", event, base.ReasonPoll, path)
+	}
+	select {
+	case event := <-broker.Notify():
+		t.Errorf("got stale event %+v", event)
+	default:
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// Kubernetes ConfigMap and Secret volume layout
+//
+//	/etc/config/app.toml -> ..data/app.toml
+//	/etc/config/..data -> ..2023_02_19_12_37_42.1234
+//	/etc/config/..2023_02_19_12_37_42.1234/app.toml
+//
+// kubelet writes an update into a new timestamped dir, points ..data_tmp at it,
+// renames ..data_tmp over ..data and removes the old timestamped dir.
+
+package fs
+
+import "path/filepath"
+
+// kubeDataDir is the symlink kubelet swaps atomically on every update
+const kubeDataDir = "..data"
+
+// watchDir returns the dir fsnotify watches.
+// For a config file addressed through ..data it is the volume root holding ..data,
+// as the dir ..data points to is removed on the next update
+func (fs *FsSource) watchDir() string {
+	for dir := fs.dir; dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if filepath.Base(dir) == kubeDataDir {
+			return filepath.Dir(dir)
+		}
+	}
+	return fs.dir
+}
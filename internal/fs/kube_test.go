@@ -0,0 +1,105 @@
+package fs
+
+import (
+	"fmt"
+	"github.com/enpsl/conf-reload/internal/log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// kubeVolume simulates the ConfigMap volume layout written by kubelet
+type kubeVolume struct {
+	t       *testing.T
+	dir     string
+	current string
+	version int
+}
+
+func newKubeVolume(t *testing.T, files map[string]string) *kubeVolume {
+	v := &kubeVolume{t: t, dir: t.TempDir()}
+	v.update(files)
+	for name := range files {
+		if err := os.Symlink(filepath.Join(kubeDataDir, name), filepath.Join(v.dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return v
+}
+
+// update performs the same steps as the kubelet atomic writer:
+// write a new timestamped dir, point ..data_tmp at it,
+// rename ..data_tmp over ..data and remove the old timestamped dir
+func (v *kubeVolume) update(files map[string]string) {
+	v.version++
+	ts := fmt.Sprintf("..2023_02_19_12_37_%02d.%d", v.version, os.Getpid())
+	if err := os.Mkdir(filepath.Join(v.dir, ts), 0755); err != nil {
+		v.t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(v.dir, ts, name), []byte(content), 0644); err != nil {
+			v.t.Fatal(err)
+		}
+	}
+	tmp := filepath.Join(v.dir, "..data_tmp")
+	if err := os.Symlink(ts, tmp); err != nil {
+		v.t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(v.dir, kubeDataDir)); err != nil {
+		v.t.Fatal(err)
+	}
+	if v.current != "" {
+		if err := os.RemoveAll(filepath.Join(v.dir, v.current)); err != nil {
+			v.t.Fatal(err)
+		}
+	}
+	v.current = ts
+}
+
+func TestFsBrokerKubeConfigMap(t *testing.T) {
+	tests := []struct {
+		desc string
+		path func(dir string) string
+	}{
+		{
+			desc: "path of the visible symlink",
+			path: func(dir string) string { return filepath.Join(dir, "app.toml") },
+		},
+		{
+			desc: "path through ..data",
+			path: func(dir string) string { return filepath.Join(dir, kubeDataDir, "app.toml") },
+		},
+	}
+	for _, tc := range tests {
+		v := newKubeVolume(t, map[string]string{"app.toml": "port=8080"})
+		err, broker := NewFs(tc.path(v.dir), log.NewLogger(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		go broker.Watch()
+		time.Sleep(50 * time.Millisecond)
+
+		for _, port := range []string{"8081", "8082"} {
+			v.update(map[string]string{"app.toml": "port=" + port})
+			select {
+			case <-broker.Notify():
+			case <-time.After(time.Second):
+				t.Fatalf("%s: broker.Watch did not notify the ..data swap", tc.desc)
+			}
+			content, err := broker.LoadContent()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(content); got != "port="+port {
+				t.Errorf("%s: got=%s, want=%s", tc.desc, got, "port="+port)
+			}
+		}
+		select {
+		case <-broker.Notify():
+			t.Errorf("%s: broker.Watch notified more than once per update", tc.desc)
+		case <-time.After(50 * time.Millisecond):
+		}
+		broker.Close()
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// process wide fsnotify watcher shared by all brokers,
+// so N brokers on one dir cost one inotify instance and one watch
+
+package fs
+
+import (
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"path/filepath"
+	"sync"
+)
+
+// subscription receives the events of one watched dir
+type subscription struct {
+	dir    string
+	events chan fsnotify.Event
+	lost   chan error // the watch is lost and the subscription is dropped from the mux
+}
+
+// watchMux reference counts watched dirs by their subscriptions
+// and fans the events of the shared watcher out to them
+type watchMux struct {
+	mu      sync.Mutex
+	watcher *fsnotify.Watcher
+	dirs    map[string]map[*subscription]struct{}
+}
+
+var mux = newWatchMux()
+
+func newWatchMux() *watchMux {
+	return &watchMux{dirs: make(map[string]map[*subscription]struct{})}
+}
+
+// subscribe watches dir, the shared watcher is created by the first subscription
+func (m *watchMux) subscribe(dir string) (*subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.watcher == nil {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			return nil, err
+		}
+		m.watcher = w
+		go m.run(w)
+	}
+	if len(m.dirs[dir]) == 0 {
+		if err := m.watcher.Add(dir); err != nil {
+			m.closeIfIdle()
+			return nil, err
+		}
+		m.dirs[dir] = make(map[*subscription]struct{})
+	}
+	sub := &subscription{
+		dir:    dir,
+		events: make(chan fsnotify.Event, 64),
+		lost:   make(chan error, 1),
+	}
+	m.dirs[dir][sub] = struct{}{}
+	return sub, nil
+}
+
+// unsubscribe drops sub, the watch of its dir is removed with the last subscription
+// and the shared watcher is closed once no dir is watched
+func (m *watchMux) unsubscribe(sub *subscription) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(sub)
+}
+
+func (m *watchMux) remove(sub *subscription) {
+	subs, ok := m.dirs[sub.dir]
+	if _, exists := subs[sub]; !ok || !exists {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(m.dirs, sub.dir)
+		_ = m.watcher.Remove(sub.dir)
+		m.closeIfIdle()
+	}
+}
+
+func (m *watchMux) closeIfIdle() {
+	if len(m.dirs) == 0 && m.watcher != nil {
+		_ = m.watcher.Close()
+		m.watcher = nil
+	}
+}
+
+func (m *watchMux) run(w *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			m.dispatch(w, event)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			m.fail(w, err)
+		}
+	}
+}
+
+// dispatch sends event to the subscriptions of its dir.
+// A removed dir is no longer watched by fsnotify, its subscriptions are dropped as lost
+func (m *watchMux) dispatch(w *fsnotify.Watcher, event fsnotify.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if w != m.watcher {
+		return
+	}
+	name := filepath.Clean(event.Name)
+	if subs, ok := m.dirs[name]; ok && event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		delete(m.dirs, name)
+		for sub := range subs {
+			sub.drop(fmt.Errorf("watched dir %s removed", name))
+		}
+		m.closeIfIdle()
+		return
+	}
+	for sub := range m.dirs[filepath.Dir(name)] {
+		select {
+		case sub.events <- event:
+		default:
+			// the broker is too slow, drop it so it recovers and reloads
+			sub.drop(fsnotify.ErrEventOverflow)
+			m.remove(sub)
+		}
+	}
+}
+
+// fail drops every subscription after a watcher error,
+// the brokers recover by subscribing again to a new watcher
+func (m *watchMux) fail(w *fsnotify.Watcher, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if w != m.watcher {
+		return
+	}
+	for dir, subs := range m.dirs {
+		for sub := range subs {
+			sub.drop(err)
+		}
+		delete(m.dirs, dir)
+	}
+	m.closeIfIdle()
+}
+
+func (sub *subscription) drop(err error) {
+	select {
+	case sub.lost <- err:
+	default:
+	}
+}
@@ -0,0 +1,69 @@
+package fs
+
+import (
+	"github.com/enpsl/conf-reload/internal/log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// subscriptions returns the number of subscriptions of dir in the shared mux
+func subscriptions(dir string) int {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	return len(mux.dirs[dir])
+}
+
+func waitSubscriptions(t *testing.T, dir string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for subscriptions(dir) != want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := subscriptions(dir); got != want {
+		t.Fatalf("subscriptions of %s got=%d, want=%d", dir, got, want)
+	}
+}
+
+func TestWatchMuxShared(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.toml")
+	if err := os.WriteFile(path, []byte("port=8080"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var brokers []*FsBroker
+	for i := 0; i < 3; i++ {
+		err, broker := NewFs(path, log.NewLogger(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		go broker.Watch()
+		brokers = append(brokers, broker)
+	}
+	waitSubscriptions(t, dir, len(brokers))
+
+	mux.mu.Lock()
+	watcher := mux.watcher
+	mux.mu.Unlock()
+	if watcher == nil {
+		t.Fatal("shared watcher was not created")
+	}
+
+	if err := os.WriteFile(path, []byte("port=8081"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i, broker := range brokers {
+		select {
+		case <-broker.Notify():
+		case <-time.After(time.Second):
+			t.Fatalf("broker %d was not notified", i)
+		}
+	}
+
+	for _, broker := range brokers {
+		broker.Close()
+	}
+	waitSubscriptions(t, dir, 0)
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// polling watcher, used where fsnotify does not fire such as NFS, FUSE and bind mounts
+
+package fs
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/enpsl/conf-reload/internal/base"
+	"os"
+	"time"
+)
+
+// fileState is the snapshot of the config file compared between polls
+type fileState struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+}
+
+// poll checks the config file every interval until the broker is closed.
+// The content hash is only computed when the stat result changes,
+// so a touch without content change does not notify
+func (fs *FsSource) poll(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := fs.stat(fileState{})
+	fs.pollHealth(last)
+	for {
+		select {
+		case <-fs.done:
+			return
+		case <-ticker.C:
+			current := fs.stat(last)
+			if current.exists != last.exists {
+				fs.pollHealth(current)
+			}
+			if current.exists && current.sum != last.sum {
+				fs.logger.Debugf("modified file:%s", fs.abs)
+				fs.changed(base.NewEvent(fs.abs, base.ReasonPoll))
+			}
+			last = current
+		}
+	}
+}
+
+// stat returns the current fileState, reusing the hash of last if stat is unchanged
+func (fs *FsSource) stat(last fileState) fileState {
+	f, err := os.Stat(fs.abs)
+	if err != nil {
+		return fileState{}
+	}
+	current := fileState{exists: true, modTime: f.ModTime(), size: f.Size()}
+	if last.exists && current.modTime.Equal(last.modTime) && current.size == last.size {
+		current.sum = last.sum
+		return current
+	}
+	content, err := os.ReadFile(fs.abs)
+	if err != nil {
+		return last
+	}
+	current.sum = sha256.Sum256(content)
+	return current
+}
+
+// pollHealth degrades the broker while the config file is missing
+func (fs *FsSource) pollHealth(state fileState) {
+	if state.exists {
+		fs.setHealth(true, nil)
+		return
+	}
+	fs.setHealth(true, fmt.Errorf("config file %s missing", fs.abs))
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// config file discovery across search paths
+
+package fs
+
+import (
+	"fmt"
+	"github.com/enpsl/conf-reload/internal/errors"
+	"github.com/enpsl/conf-reload/internal/format"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Search looks for the config file name in paths followed by the default
+// search directories, and returns the first existing file.
+// If name has no registered ext, every registered ext is tried in the order of candidates.
+// The error lists every location that was attempted
+func Search(name string, paths ...string) (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		wd = "."
+	}
+	return search(name, searchDirs(appName(name), paths, wd))
+}
+
+// searchDirs returns the directories to search in order:
+// paths, ./, $XDG_CONFIG_HOME/<app>, $HOME/.config/<app>, /etc/<app>,
+// and then every parent of the working directory
+func searchDirs(app string, paths []string, wd string) []string {
+	dirs := append([]string{}, paths...)
+	dirs = append(dirs, wd)
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		dirs = append(dirs, filepath.Join(xdg, app))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config", app))
+	}
+	dirs = append(dirs, filepath.Join("/etc", app))
+	for dir := filepath.Dir(wd); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	return dirs
+}
+
+func search(name string, dirs []string) (string, error) {
+	var tried []string
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		for _, file := range candidates(name) {
+			path, err := filepath.Abs(filepath.Join(dir, file))
+			if err != nil || seen[path] {
+				continue
+			}
+			seen[path] = true
+			tried = append(tried, path)
+			if f, err := os.Stat(path); err == nil && f.Mode().IsRegular() {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: %s, tried [%s]", errors.ErrConfigNotFound, name, strings.Join(tried, ", "))
+}
+
+// extPrecedence lists the exts tried first for a name without ext,
+// so a directory holding app.toml and app.env always resolves to app.toml
+var extPrecedence = []string{".toml", ".yaml", ".yml", ".json"}
+
+// candidates returns the file names to try for name:
+// name itself if it has a registered ext, otherwise name with the exts of extPrecedence,
+// followed by every other registered ext in alphabetical order
+func candidates(name string) []string {
+	if format.ExtParser(name) != "" {
+		return []string{name}
+	}
+	exts := format.Exts()
+	names := make([]string, 0, len(exts))
+	first := make(map[string]bool, len(extPrecedence))
+	for _, ext := range extPrecedence {
+		first[ext] = true
+		if format.ExtParser(name+ext) != "" {
+			names = append(names, name+ext)
+		}
+	}
+	for _, ext := range exts {
+		if !first[ext] {
+			names = append(names, name+ext)
+		}
+	}
+	return names
+}
+
+// appName strips any registered ext from name
+func appName(name string) string {
+	name = filepath.Base(name)
+	if format.ExtParser(name) != "" {
+		return strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name
+}
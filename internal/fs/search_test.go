@@ -0,0 +1,82 @@
+package fs
+
+import (
+	"github.com/enpsl/conf-reload/internal/errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(root, "app.yml")
+	if err := os.WriteFile(want, []byte("port: 8080"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		desc string
+		name string
+		dirs []string
+	}{
+		{
+			desc: "walk up from working directory",
+			name: "app",
+			dirs: searchDirs("app", nil, nested),
+		},
+		{
+			desc: "explicit ext",
+			name: "app.yml",
+			dirs: []string{nested, root},
+		},
+	}
+	for _, tc := range tests {
+		got, err := search(tc.name, tc.dirs)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.desc, err)
+		}
+		if got != want {
+			t.Errorf("%s: got=%s, want=%s", tc.desc, got, want)
+		}
+	}
+}
+
+func TestSearchPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"app.env", "app.hcl", "app.ini", "app.json", "app.yaml", "app.toml"} {
+		if err := os.WriteFile(filepath.Join(dir, file), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"app.toml", "app.yaml", "app.json", "app.env"}
+	for _, file := range want {
+		got, err := search("app", []string{dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != filepath.Join(dir, file) {
+			t.Errorf("got=%s, want=%s", got, filepath.Join(dir, file))
+		}
+		if err = os.Remove(got); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSearchNotFound(t *testing.T) {
+	dir := t.TempDir()
+	_, err := search("app", []string{dir})
+	if !errors.Is(err, errors.ErrConfigNotFound) {
+		t.Fatalf("got=%v, want=%v", err, errors.ErrConfigNotFound)
+	}
+	for _, file := range candidates("app") {
+		if !strings.Contains(err.Error(), filepath.Join(dir, file)) {
+			t.Errorf("error %q does not report attempted location %s", err, file)
+		}
+	}
+}
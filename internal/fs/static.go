@@ -0,0 +1,53 @@
+// Copyright 2023 enpsl. All rights reserved.
+
+// config content held in memory
+
+package fs
+
+import (
+	"github.com/enpsl/conf-reload/internal/base"
+	"sync"
+)
+
+// StaticSource serves content that never changes, such as embedded defaults
+type StaticSource struct {
+	content  []byte
+	notifyCh chan base.Event
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewStatic returns the StaticSource of content
+func NewStatic(content []byte) *StaticSource {
+	return &StaticSource{
+		content:  content,
+		notifyCh: make(chan base.Event),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *StaticSource) LoadContent() ([]byte, error) {
+	return s.content, nil
+}
+
+// Watch blocks until the source is closed, the content never changes
+func (s *StaticSource) Watch() {
+	<-s.done
+}
+
+func (s *StaticSource) Notify() <-chan base.Event {
+	return s.notifyCh
+}
+
+// Health reports the source as not watching
+func (s *StaticSource) Health() base.Health {
+	return base.Health{}
+}
+
+func (s *StaticSource) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+		close(s.notifyCh)
+	})
+	return nil
+}
@@ -0,0 +1,49 @@
+package conf_reload
+
+import (
+	"fmt"
+	"github.com/enpsl/conf-reload/internal/base"
+	"github.com/enpsl/conf-reload/internal/fs"
+	"os"
+)
+
+// loadLastKnownGood
+// Apply the config persisted in LastKnownGood after the config file failed to load with cause
+func (e *Engine) loadLastKnownGood(cause error) error {
+	content, err := os.ReadFile(e.LastKnownGood)
+	if err != nil {
+		return fmt.Errorf("%w, last known good config unavailable: %s", cause, err.Error())
+	}
+	if err = e.detectFormat(content); err == nil {
+		err = e.apply(content, base.NewEvent(e.LastKnownGood, base.ReasonLastKnownGood))
+	}
+	if err != nil {
+		return fmt.Errorf("%w, last known good config unusable: %s", cause, err.Error())
+	}
+	e.mu.Lock()
+	e.lkgReason = fmt.Sprintf("config loaded from last known good %s: %s", e.LastKnownGood, cause.Error())
+	e.mu.Unlock()
+	e.Logger.Errorf("config file failed to load, running on last known good config %s: %s", e.LastKnownGood, cause.Error())
+	return nil
+}
+
+// persist
+// Write the applied content to LastKnownGood, e.mu must be held
+func (e *Engine) persist(content []byte, event base.Event) {
+	if e.LastKnownGood == "" || event.Reason == base.ReasonLastKnownGood {
+		return
+	}
+	if err := fs.WriteFileAtomic(e.LastKnownGood, content, 0600); err != nil {
+		e.Logger.Warnf("persist last known good config %s: %s", e.LastKnownGood, err.Error())
+	}
+}
+
+// recovered
+// Clear the last known good fallback once a config not loaded from it applies, e.mu must be held
+func (e *Engine) recovered(event base.Event) {
+	if e.lkgReason == "" || event.Reason == base.ReasonLastKnownGood {
+		return
+	}
+	e.lkgReason = ""
+	e.Logger.Infof("config applied from %s, no longer running on last known good config", event.Name)
+}
@@ -0,0 +1,47 @@
+package conf_reload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEngineLastKnownGood(t *testing.T) {
+	lkg := filepath.Join(t.TempDir(), "app.toml.lkg")
+	_, path := newTestEngine(t, ".toml", "port = 8080", WithLastKnownGood(lkg))
+	content, err := os.ReadFile(lkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "port = 8080" {
+		t.Errorf("persisted got=%s, want=%s", content, "port = 8080")
+	}
+
+	if err := os.WriteFile(path, []byte("port = "), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine()
+	if err := e.Load(path, WithWatched(false), WithLogLevel(1), WithLastKnownGood(lkg)); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetInt("port"); got != 8080 {
+		t.Errorf("got=%d, want=%d", got, 8080)
+	}
+	if h := e.Health(); !h.Degraded {
+		t.Errorf("got=%+v, want degraded", h)
+	}
+
+	if err := os.WriteFile(path, []byte("port = 8081"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if h := e.Health(); h.Degraded {
+		t.Errorf("got=%+v, want not degraded", h)
+	}
+	if content, _ = os.ReadFile(lkg); string(content) != "port = 8081" {
+		t.Errorf("persisted got=%s, want=%s", content, "port = 8081")
+	}
+}
@@ -0,0 +1,190 @@
+package conf_reload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEngineLoadStatic(t *testing.T) {
+	const content = "[server.http]\nhost = \"0.0.0.0\"\nport = 8080"
+	tests := []struct {
+		desc string
+		load func(e *Engine) error
+	}{
+		{
+			desc: "bytes",
+			load: func(e *Engine) error {
+				return e.LoadBytes([]byte(content), "toml", WithLogLevel(1))
+			},
+		},
+		{
+			desc: "bytes sniffed",
+			load: func(e *Engine) error {
+				return e.LoadBytes([]byte(content), FormatAuto, WithLogLevel(1))
+			},
+		},
+		{
+			desc: "reader",
+			load: func(e *Engine) error {
+				return e.LoadReader(strings.NewReader(content), "toml", WithLogLevel(1))
+			},
+		},
+		{
+			desc: "fs",
+			load: func(e *Engine) error {
+				fsys := fstest.MapFS{"conf/app.toml": {Data: []byte(content)}}
+				return e.LoadFS(fsys, "conf/app.toml", WithLogLevel(1))
+			},
+		},
+	}
+	for _, tc := range tests {
+		e := NewEngine()
+		if err := tc.load(e); err != nil {
+			t.Fatalf("%s: %v", tc.desc, err)
+		}
+		if e.Health().Watching {
+			t.Errorf("%s: static config is watched", tc.desc)
+		}
+		var http = &Http{}
+		if err := e.DecodeToStruct("server.http", http); err != nil {
+			t.Fatalf("%s: %v", tc.desc, err)
+		}
+		if http.Port != 8080 || http.Host != "0.0.0.0" {
+			t.Errorf("%s: host=%s, want=%s| port=%d, want=%d", tc.desc, http.Host, "0.0.0.0", http.Port, 8080)
+		}
+	}
+}
+
+func TestEngineLoadFSNotFound(t *testing.T) {
+	e := NewEngine()
+	if err := e.LoadFS(fstest.MapFS{}, "app.toml"); err == nil {
+		t.Error("missing file loaded")
+	}
+}
+
+func TestEngineLoadStaticError(t *testing.T) {
+	e := NewEngine()
+	if err := e.LoadBytes([]byte("port = 8080"), "toml", WithLogLevel(1)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		desc string
+		data string
+		name string
+	}{
+		{desc: "unknown format", data: "port: 8081", name: "conf"},
+		{desc: "unparsable data", data: "port = ", name: "toml"},
+		{desc: "ambiguous data", data: "# nothing", name: FormatAuto},
+	}
+	for _, tc := range tests {
+		if err := e.LoadBytes([]byte(tc.data), tc.name); err == nil {
+			t.Errorf("%s: no error", tc.desc)
+		}
+		if got := e.GetInt("port"); got != 8080 {
+			t.Errorf("%s: got=%d, want=%d", tc.desc, got, 8080)
+		}
+		if err := e.Reload(context.Background()); err != nil {
+			t.Errorf("%s: previous config not kept: %v", tc.desc, err)
+		}
+	}
+}
+
+func TestEngineReloadUnchangedContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(path, []byte("SERVER__PORT=8080"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine()
+	if err := e.load(path, WithWatched(false), WithLogLevel(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.load(path, WithEnvSeparator("__")); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetInt("server.port"); got != 8080 {
+		t.Errorf("parse options: got=%d, want=%d", got, 8080)
+	}
+
+	if err := e.LoadBytes([]byte("a = 1"), "toml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadBytes([]byte("a = 1"), "yaml"); err == nil {
+		t.Error("format: content parsed as toml applied as yaml")
+	}
+	reject := func(config map[string]interface{}) error { return errors.New("rejected") }
+	if err := e.LoadBytes([]byte("a = 1"), "toml", WithValidator(reject)); !errors.Is(err, ErrValidation) {
+		t.Errorf("validators: got=%v, want=%v", err, ErrValidation)
+	}
+}
+
+func TestEngineLoadFromSearchPaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.toml"), []byte("[server]\nport = 8080"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine()
+	if err := e.LoadFromSearchPaths("app", []string{dir}, WithWatched(false), WithLogLevel(1), WithLevelSplit("/")); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetInt("server/port"); got != 8080 {
+		t.Errorf("got=%d, want=%d", got, 8080)
+	}
+}
+
+func TestEngineLoadConcurrentRead(t *testing.T) {
+	e := NewEngine()
+	if err := e.LoadBytes([]byte("port = 8080"), "toml", WithLogLevel(1)); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			if got := e.GetInt("port"); got != 8080 && got != 8081 {
+				t.Errorf("got=%d, want 8080 or 8081", got)
+			}
+			e.Health()
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		_ = e.LoadBytes([]byte(fmt.Sprintf("port = %d", 8080+i%2)), "toml")
+	}
+	<-done
+
+	cache := e.LocalStorage
+	if err := e.LoadBytes([]byte("port = "), "toml"); err == nil {
+		t.Fatal("unparsable data loaded")
+	}
+	if e.LocalStorage != cache {
+		t.Error("failed Load replaced the cache")
+	}
+}
+
+func TestEngineLoadResetsValidators(t *testing.T) {
+	e := NewEngine()
+	calls := 0
+	count := func(config map[string]interface{}) error {
+		calls++
+		return nil
+	}
+	for i := 0; i < 3; i++ {
+		if err := e.LoadBytes([]byte(fmt.Sprintf("port = %d", 8080+i)), "toml", WithLogLevel(1), WithValidator(count)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 3 || len(e.Validators) != 1 {
+		t.Errorf("calls got=%d, want=%d| validators got=%d, want=%d", calls, 3, len(e.Validators), 1)
+	}
+	if err := e.LoadBytes([]byte("port = 9090"), "toml"); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Validators) != 0 {
+		t.Errorf("validators got=%d, want=%d", len(e.Validators), 0)
+	}
+}
@@ -0,0 +1,101 @@
+package conf_reload
+
+import (
+	"context"
+	"github.com/enpsl/conf-reload/internal/base"
+	"github.com/enpsl/conf-reload/internal/errors"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// ErrNotLoaded is returned by Reload before the engine loaded a config
+var ErrNotLoaded = errors.ErrNotLoaded
+
+// Reload the content from the broker and apply it synchronously,
+// it works whether the broker is watched or not.
+// While reloads are held, the reload is queued and ErrReloadHeld is returned,
+// before the first successful Load ErrNotLoaded is returned
+func (e *Engine) Reload(ctx context.Context) error {
+	return e.reload(ctx, base.NewEvent(e.path, base.ReasonManual))
+}
+
+// reload
+// Every reload, triggered by the broker, Reload or a signal, goes through here.
+// Load the content from the source and apply it, failures are counted
+func (e *Engine) reload(ctx context.Context, event base.Event) error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	e.Logger.Debugf("reload %s: %s", event.Name, event.Reason)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if e.Source == nil {
+		return errors.ErrFormat(errors.ErrNotLoaded, nil)
+	}
+	if reason := e.hold(event); reason != "" {
+		e.Logger.Infof("reload %s held: %s", event.Name, reason)
+		return errors.ErrFormat(errors.ErrReloadHeld, nil)
+	}
+	content, err := e.Source.LoadContent()
+	if err != nil {
+		e.mu.Lock()
+		e.stats.Failed++
+		e.mu.Unlock()
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	if len(content) == 0 && watched(event.Reason) {
+		// a truncating write such as os.WriteFile notifies before the content is written,
+		// the write of the content notifies again. Reload applies an emptied file on purpose
+		e.mu.Lock()
+		e.stats.Skipped++
+		e.mu.Unlock()
+		e.Logger.Warnf("reload %s skipped: the file is empty, call Reload to apply an emptied config", event.Name)
+		return nil
+	}
+	revision := e.Revision()
+	if err = e.apply(content, event); err != nil {
+		return err
+	}
+	if e.Revision() != revision {
+		e.chargeReload(time.Now())
+	}
+	return nil
+}
+
+// watched reports whether reason is a change seen by the source watcher
+func watched(reason base.EventReason) bool {
+	switch reason {
+	case base.ReasonWrite, base.ReasonSymlink, base.ReasonPoll, base.ReasonRecover:
+		return true
+	}
+	return false
+}
+
+// watchSignals reloads on every ReloadSignals received,
+// stopping the signals registered by the previous Load.
+// Called with reloadMu held
+func (e *Engine) watchSignals() {
+	if e.signals != nil {
+		signal.Stop(e.signals)
+		close(e.signals)
+		e.signals = nil
+	}
+	if len(e.ReloadSignals) == 0 {
+		return
+	}
+	ch := make(chan os.Signal, 1)
+	e.signals = ch
+	signal.Notify(ch, e.ReloadSignals...)
+	go func() {
+		for sig := range ch {
+			event := base.NewEvent(e.path, base.ReasonSignal)
+			if err := e.reload(context.Background(), event); err != nil && !errors.Is(err, ErrReloadHeld) {
+				e.Logger.Errorf("reload on %s: %s", sig, err.Error())
+			}
+		}
+	}()
+}
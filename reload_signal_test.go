@@ -0,0 +1,52 @@
+//go:build !windows
+
+package conf_reload
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestEngineReloadSignal(t *testing.T) {
+	e, path := newTestEngine(t, ".toml", "port = 8080", WithReloadSignal(syscall.SIGHUP))
+	if err := os.WriteFile(path, []byte("port = 8081"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for e.GetInt("port") != 8081 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := e.GetInt("port"); got != 8081 {
+		t.Errorf("got=%d, want=%d", got, 8081)
+	}
+
+	// a second Load replaces the registration instead of adding one
+	first := e.signals
+	if err := e.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if e.signals == first {
+		t.Error("Load kept the previous signal registration")
+	}
+	if _, ok := <-first; ok {
+		t.Error("previous signal channel still open")
+	}
+	if err := os.WriteFile(path, []byte("port = 8082"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	deadline = time.Now().Add(time.Second)
+	for e.GetInt("port") != 8082 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := e.GetInt("port"); got != 8082 {
+		t.Errorf("got=%d, want=%d", got, 8082)
+	}
+}
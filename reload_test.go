@@ -0,0 +1,74 @@
+package conf_reload
+
+import (
+	"context"
+	"errors"
+	"github.com/enpsl/conf-reload/internal/base"
+	"os"
+	"testing"
+)
+
+func TestEngineReload(t *testing.T) {
+	e, path := newTestEngine(t, ".toml", "port = 8080")
+	if err := os.WriteFile(path, []byte("port = 8081"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetInt("port"); got != 8081 {
+		t.Errorf("got=%d, want=%d", got, 8081)
+	}
+
+	if err := os.WriteFile(path, []byte("port = "), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Reload(context.Background()); err == nil {
+		t.Error("Reload of invalid content returned no error")
+	}
+	if got := e.GetInt("port"); got != 8081 {
+		t.Errorf("got=%d, want=%d", got, 8081)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := e.Reload(ctx); err != context.Canceled {
+		t.Errorf("got=%v, want=%v", err, context.Canceled)
+	}
+}
+
+func TestEngineReloadNotLoaded(t *testing.T) {
+	e := NewEngine()
+	if err := e.Reload(context.Background()); !errors.Is(err, ErrNotLoaded) {
+		t.Errorf("got=%v, want=%v", err, ErrNotLoaded)
+	}
+	if err := e.LoadBytes([]byte("port = "), "toml", WithLogLevel(1)); err == nil {
+		t.Fatal("unparsable data loaded")
+	}
+	if err := e.Reload(context.Background()); !errors.Is(err, ErrNotLoaded) {
+		t.Errorf("after a failed Load: got=%v, want=%v", err, ErrNotLoaded)
+	}
+}
+
+func TestEngineReloadEmptyWrite(t *testing.T) {
+	e, path := newTestEngine(t, ".toml", "port = 8080")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.reload(context.Background(), base.NewEvent(path, base.ReasonWrite)); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetInt("port"); got != 8080 {
+		t.Errorf("truncated file applied: got=%d, want=%d", got, 8080)
+	}
+	if stats := e.Stats(); stats.Skipped != 1 {
+		t.Errorf("skipped got=%d, want=%d", stats.Skipped, 1)
+	}
+
+	if err := e.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Revision(); got != 2 {
+		t.Errorf("Reload of the emptied file: revision got=%d, want=%d", got, 2)
+	}
+}
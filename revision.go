@@ -0,0 +1,42 @@
+package conf_reload
+
+import "context"
+
+// Revision returns the revision of the current config,
+// bumped by every applied config starting at 1 for the initial load
+func (e *Engine) Revision() uint64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.revision
+}
+
+// WaitForRevision blocks until the current config is at least revision, or ctx is done
+func (e *Engine) WaitForRevision(ctx context.Context, revision uint64) error {
+	for {
+		e.mu.RLock()
+		current, changed := e.revision, e.changed
+		e.mu.RUnlock()
+		if current >= revision {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// WaitForChange blocks until the next config is applied, or ctx is done,
+// and returns the revision of the applied config
+func (e *Engine) WaitForChange(ctx context.Context) (uint64, error) {
+	e.mu.RLock()
+	changed := e.changed
+	e.mu.RUnlock()
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-changed:
+		return e.Revision(), nil
+	}
+}
@@ -0,0 +1,47 @@
+package conf_reload
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEngineWaitForRevision(t *testing.T) {
+	e, path := newTestEngine(t, ".toml", "port = 8080", WithWatched(true))
+	defer e.Source.Close()
+	if got := e.Revision(); got != 1 {
+		t.Fatalf("got=%d, want=%d", got, 1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	changed := make(chan uint64, 1)
+	go func() {
+		rev, err := e.WaitForChange(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		changed <- rev
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("port = 8081"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.WaitForRevision(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetInt("port"); got != 8081 {
+		t.Errorf("got=%d, want=%d", got, 8081)
+	}
+	if rev := <-changed; rev != 2 {
+		t.Errorf("WaitForChange got=%d, want=%d", rev, 2)
+	}
+
+	short, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := e.WaitForRevision(short, 3); err != context.DeadlineExceeded {
+		t.Errorf("got=%v, want=%v", err, context.DeadlineExceeded)
+	}
+}
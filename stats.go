@@ -0,0 +1,11 @@
+package conf_reload
+
+import "time"
+
+// ReloadStats counts the reloads handled by an Engine
+type ReloadStats struct {
+	Applied     uint64    // configs applied, including the initial load
+	Skipped     uint64    // reloads skipped because the config did not change or the watched file was empty
+	Failed      uint64    // reloads failed to load or parse
+	LastApplied time.Time // time the current config was applied
+}
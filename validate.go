@@ -0,0 +1,66 @@
+package conf_reload
+
+import (
+	"fmt"
+	"github.com/enpsl/conf-reload/internal/errors"
+	"reflect"
+)
+
+// ErrValidation is returned when a validator rejects a config, which is not applied
+var ErrValidation = errors.ErrValidation
+
+// Validator checks a parsed config before it is applied, an error rejects the config.
+// It runs while the engine is locked and must not call the engine getters
+type Validator func(config map[string]interface{}) error
+
+// Validate
+// Check whether content would apply cleanly: parse it with the format, run every validator
+// and return the Diff against the current config. Nothing is mutated.
+// The format and validators are those of the last Load, before it ErrNotLoaded is returned
+func (e *Engine) Validate(content []byte) (*Diff, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.Format == nil {
+		return nil, errors.ErrFormat(errors.ErrNotLoaded, nil)
+	}
+	err, m := e.Format.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	if err = e.validate(m); err != nil {
+		return nil, err
+	}
+	return e.diff(e.Configure, m), nil
+}
+
+// validate runs every validator on config
+func (e *Engine) validate(config map[string]interface{}) error {
+	for _, v := range e.Validators {
+		if err := v(config); err != nil {
+			return fmt.Errorf("%w: %s", ErrValidation, err)
+		}
+	}
+	return nil
+}
+
+// decodeCheck decodes the value of key into a new value of the type of prototype
+func (e *Engine) decodeCheck(config map[string]interface{}, key string, prototype interface{}) error {
+	t := reflect.TypeOf(prototype)
+	if t == nil {
+		return errors.New("nil prototype")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var value interface{} = config
+	if key != "" {
+		value = e.lookup(config, key)
+		if value == nil {
+			return errors.ErrFormat(errors.ErrInvalidKey, nil)
+		}
+	}
+	if err := e.Decoder.Decode(value, reflect.New(t).Interface(), e.WeaklyTypedInput); err != nil {
+		return fmt.Errorf("decode %s into %s: %w", key, t, err)
+	}
+	return nil
+}
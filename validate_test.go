@@ -0,0 +1,92 @@
+package conf_reload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestEngineValidateNotLoaded(t *testing.T) {
+	if _, err := NewEngine().Validate([]byte("port = 8080")); !errors.Is(err, ErrNotLoaded) {
+		t.Errorf("got=%v, want=%v", err, ErrNotLoaded)
+	}
+}
+
+func TestEngineValidate(t *testing.T) {
+	portRange := func(config map[string]interface{}) error {
+		server, _ := config["server"].(map[string]interface{})
+		if port, _ := server["port"].(int64); port > 65535 {
+			return errors.New("port out of range")
+		}
+		return nil
+	}
+	e, path := newTestEngine(t, ".toml", "[server]\nhost = \"0.0.0.0\"\nport = 8080\ntimeout = \"10s\"",
+		WithValidator(portRange),
+		WithStructValidator("server", &Http{}),
+	)
+
+	tests := []struct {
+		desc    string
+		content string
+		keys    []string
+		wantErr bool
+		invalid bool
+	}{
+		{
+			desc:    "unchanged",
+			content: "[server]\nhost = \"0.0.0.0\"\nport = 8080\ntimeout = \"10s\"",
+		},
+		{
+			desc:    "changed added and removed keys",
+			content: "[server]\nhost = \"0.0.0.0\"\nport = 8081\ndebug = true",
+			keys:    []string{"server.debug", "server.port", "server.timeout"},
+		},
+		{
+			desc:    "rejected by validator",
+			content: "[server]\nport = 80800",
+			wantErr: true,
+			invalid: true,
+		},
+		{
+			desc:    "rejected by struct validator",
+			content: "[server]\nport = \"http\"",
+			wantErr: true,
+			invalid: true,
+		},
+		{
+			desc:    "parse error",
+			content: "[server",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		diff, err := e.Validate([]byte(tc.content))
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("%s: err=%v, wantErr=%t", tc.desc, err, tc.wantErr)
+		}
+		if invalid := errors.Is(err, ErrValidation); invalid != tc.invalid {
+			t.Errorf("%s: errors.Is(%v, ErrValidation)=%t, want=%t", tc.desc, err, invalid, tc.invalid)
+		}
+		if err != nil {
+			continue
+		}
+		if keys := diff.Keys(); len(keys) != len(tc.keys) || (len(keys) > 0 && !reflect.DeepEqual(keys, tc.keys)) {
+			t.Errorf("%s: got=%v, want=%v", tc.desc, keys, tc.keys)
+		}
+	}
+	if got := e.GetInt("server.port"); got != 8080 {
+		t.Errorf("Validate mutated the config: got=%d, want=%d", got, 8080)
+	}
+
+	if err := os.WriteFile(path, []byte("[server]\nport = 80800"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Reload(context.Background()); !errors.Is(err, ErrValidation) {
+		t.Errorf("got=%v, want=%v", err, ErrValidation)
+	}
+	if got := e.GetInt("server.port"); got != 8080 {
+		t.Errorf("got=%d, want=%d", got, 8080)
+	}
+}